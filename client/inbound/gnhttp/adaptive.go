@@ -0,0 +1,117 @@
+package gnhttp
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jom-io/gorig-node/client/register"
+	"github.com/jom-io/gorig-node/gncfg"
+	"github.com/jom-io/gorig-node/internal/stats"
+	"math"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	adaptiveMinLimit    = 1
+	adaptiveTolerance   = 1.5 // handler latency may reach 1.5x its baseline before the limit shrinks
+	adaptiveSmoothing   = 0.2 // share of each new estimate blended into the limit
+	adaptiveShortWindow = 10  // samples behind the current latency
+	adaptiveLongWindow  = 500 // samples behind the baseline latency
+)
+
+// adaptiveLimiter learns a node-wide concurrency limit from handler latency, gradient style:
+// when calls start queueing inside the node the current latency drifts above the baseline and
+// the limit shrinks in proportion; while latency holds it grows by sqrt(limit). Every call is
+// admitted until the first latency sample sets the baseline, then the limit starts at the
+// configured ceiling.
+//
+// One limiter serves all services of the node, since they share its CPU and connections: a
+// service that slows down raises the latency of the whole node and sheds calls to the others
+// as well. Isolate services from each other with the per-service MaxConcurrency.
+type adaptiveLimiter struct {
+	inFlight atomic.Int64
+	limit    atomic.Int64 // 0: no baseline yet
+
+	mu       sync.Mutex
+	estimate float64
+	shortRTT float64 // seconds
+	longRTT  float64 // seconds
+}
+
+var adaptive = newAdaptiveLimiter()
+
+func newAdaptiveLimiter() *adaptiveLimiter {
+	return &adaptiveLimiter{}
+}
+
+// reset forgets the learned limit, the baseline and past shedding; calls in flight still
+// release normally.
+func (l *adaptiveLimiter) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.estimate, l.shortRTT, l.longRTT = 0, 0, 0
+	l.limit.Store(0)
+	stats.SetConcurrencyLimit(0)
+	stats.ClearShed()
+}
+
+// acquire admits a call while fewer than the learned limit are in flight.
+func (l *adaptiveLimiter) acquire() bool {
+	if limit := l.limit.Load(); l.inFlight.Add(1) > limit && limit > 0 {
+		l.inFlight.Add(-1)
+		return false
+	}
+	return true
+}
+
+// release ends an admitted call. A positive rtt is the handler latency and updates the limit,
+// which never exceeds maxLimit.
+func (l *adaptiveLimiter) release(rtt time.Duration, maxLimit int) {
+	inFlight := l.inFlight.Add(-1) + 1
+	if rtt <= 0 {
+		return
+	}
+	sample := rtt.Seconds()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.longRTT == 0 {
+		l.shortRTT, l.longRTT = sample, sample
+		l.estimate = float64(maxLimit)
+	}
+	l.shortRTT += (sample - l.shortRTT) / adaptiveShortWindow
+	l.longRTT += (sample - l.longRTT) / adaptiveLongWindow
+	if l.longRTT > l.shortRTT {
+		// latency improved, e.g. a slow dependency recovered: that is the new baseline
+		l.longRTT = l.shortRTT
+	}
+
+	gradient := math.Max(0.5, math.Min(1, adaptiveTolerance*l.longRTT/l.shortRTT))
+	next := l.estimate*gradient + math.Sqrt(l.estimate)
+	if float64(inFlight) < l.estimate/2 {
+		// light traffic says nothing about how much more the node could take
+		next = math.Min(next, l.estimate)
+	}
+	l.estimate = l.estimate*(1-adaptiveSmoothing) + next*adaptiveSmoothing
+	l.estimate = math.Max(adaptiveMinLimit, math.Min(float64(maxLimit), l.estimate))
+	l.limit.Store(int64(l.estimate))
+	stats.SetConcurrencyLimit(int64(l.estimate))
+}
+
+// admitAdaptive applies gn.limit.adaptive. It answers the call itself with a retryable 503 and
+// returns false when the node is over its learned limit; otherwise done must be called once the
+// call ends, with the handler latency or 0 when the handler did not complete.
+func admitAdaptive(c *gin.Context) (done func(rtt time.Duration), ok bool) {
+	cfg := gncfg.Current()
+	if !cfg.AdaptiveLimit {
+		return func(time.Duration) {}, true
+	}
+	if !adaptive.acquire() {
+		stats.Shed()
+		writeError(c, http.StatusServiceUnavailable, register.NewError(register.CodeOverloaded, "node is shedding load").
+			WithDetail("limit", "adaptive").WithRetryable(true))
+		return nil, false
+	}
+	return func(rtt time.Duration) { adaptive.release(rtt, cfg.AdaptiveMaxLimit) }, true
+}
@@ -0,0 +1,71 @@
+package gnhttp
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jom-io/gorig-node/client/register"
+	"github.com/jom-io/gorig-node/internal/stats"
+	"net/http"
+)
+
+// registerAdminRoutes mounts read-only introspection endpoints. They describe every service
+// of the node, not only the ones served by this listener, so they take the client certificate
+// API routes require and admit the callers allowed by register.UseAdminAllow.
+func registerAdminRoutes(router *gin.Engine) {
+	admin := router.Group("/_gn", requireClientCert(), authorizeAdmin())
+	admin.GET("/services", func(c *gin.Context) {
+		c.JSON(http.StatusOK, register.Statuses())
+	})
+	admin.GET("/apis/:service", func(c *gin.Context) {
+		srv, ok := register.RegisteredServers()[c.Param("service")]
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "service not found"})
+			return
+		}
+		c.JSON(http.StatusOK, srv.Apis)
+	})
+	admin.GET("/hubs", func(c *gin.Context) {
+		c.JSON(http.StatusOK, register.Hubs())
+	})
+	admin.GET("/health", func(c *gin.Context) {
+		statuses := register.Statuses()
+		registered := 0
+		for _, st := range statuses {
+			if st.Registered {
+				registered++
+			}
+		}
+		status := "ok"
+		if Draining() {
+			status = "draining"
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"status":            status,
+			"draining":          Draining(),
+			"in_flight":         InFlight(),
+			"services":          len(statuses),
+			"registered":        registered,
+			"shedding":          stats.Shedding(),
+			"concurrency_limit": stats.ConcurrencyLimit(), // 0 unless gn.limit.adaptive is on
+		})
+	})
+}
+
+// authorizeAdmin authenticates the caller like API routes do and checks the admin Allow list.
+func authorizeAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		caller, err := register.Authenticate(c.Request)
+		if err != nil {
+			writeError(c, http.StatusUnauthorized, register.NewError(register.CodeUnauthenticated, err.Error()))
+			return
+		}
+		if e := register.AuthorizeAdmin(caller); e != nil {
+			status := http.StatusForbidden
+			if e.Code == register.CodeUnauthenticated {
+				status = http.StatusUnauthorized
+			}
+			writeError(c, status, e)
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,48 +1,179 @@
 package gnhttp
 
 import (
+	"context"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/jom-io/gorig-node/client/register"
+	"github.com/jom-io/gorig/apix"
 	"io"
+	"math"
+	"net/http"
 	"reflect"
+	"strconv"
+	"time"
 )
 
 func handleAPIRequest(srv *register.ServerRegister, api register.ApiInfo, c *gin.Context) {
 	// 1. Locate handler
-	fn, ok := srv.FnMap[api.Method]
+	if _, ok := srv.FnMap[api.Method]; !ok {
+		writeError(c, http.StatusNotFound, register.NewError(register.CodeNotFound, "method not found"))
+		return
+	}
+
+	// 2. Identify the caller and check the method's Allow list before touching the body
+	caller, err := register.Authenticate(c.Request)
+	if err != nil {
+		writeError(c, http.StatusUnauthorized, register.NewError(register.CodeUnauthenticated, err.Error()))
+		return
+	}
+	if e := srv.Authorize(api.Method, caller); e != nil {
+		status := http.StatusForbidden
+		if e.Code == register.CodeUnauthenticated {
+			status = http.StatusUnauthorized
+		}
+		writeError(c, status, e)
+		return
+	}
+	if caller != nil {
+		c.Request = c.Request.WithContext(register.WithCaller(c.Request.Context(), caller))
+	}
+
+	// 3. Shed the call early when the node is over its adaptive concurrency limit
+	done, ok := admitAdaptive(c)
 	if !ok {
-		c.JSON(404, gin.H{"error": "method not found"})
 		return
 	}
+	var callTime time.Duration
+	defer func() { done(callTime) }()
+
+	// 4. Apply the service and method rate and concurrency limits
+	release, retryAfter, e := srv.Acquire(api.Method)
+	if e != nil {
+		if retryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		}
+		writeError(c, http.StatusTooManyRequests, e)
+		return
+	}
+	defer release()
 
 	meta := srv.MethodMeta[api.Method]
+	reqCodec, respCodec := negotiateCodecs(c)
 
-	// 2. Read body (wrapped request)
+	// 5. Read body (wrapped request)
 	body, err := io.ReadAll(c.Request.Body)
 	if err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		writeError(c, http.StatusBadRequest, register.NewError(register.CodeBadRequest, err.Error()))
 		return
 	}
 
-	// 3. Unpack arguments
-	args, err := register.UnpackRequest(meta, body, reflect.ValueOf(c))
+	// 6. Unpack arguments
+	args, err := register.UnpackRequestWith(reqCodec, meta, body, reflect.ValueOf(c))
 	if err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		writeError(c, http.StatusBadRequest, register.NewError(register.CodeBadRequest, err.Error()))
 		return
 	}
 
-	// 4. Call original function
-	results := fn.Call(args)
+	// 7. Bound the call by the caller's deadline and the method timeout, then call original function
+	cancel, ok := applyDeadline(c, srv.TimeoutOf(api.Method))
+	if !ok {
+		return
+	}
+	defer cancel()
+	started := time.Now()
+	results, callErr, ok := callHandler(c, srv, api.Method, args)
+	if !ok {
+		return
+	}
+	callTime = time.Since(started)
 
-	// 5. Pack response
-	respBytes, err := register.PackResponse(meta, results)
+	// 8. Pack response
+	var resp *register.WrappedResponse
+	if callErr != nil {
+		resp = register.FailedResponse(callErr)
+	} else if resp, err = register.NewResponseWith(respCodec, meta, results); err != nil {
+		writeError(c, http.StatusInternalServerError, register.NewError(register.CodeInternal, err.Error()))
+		return
+	}
+	if resp.Error != "" {
+		c.Set(callErrorKey, resp.Error)
+	}
+	if resp.ErrorInfo != nil && resp.ErrorInfo.TraceID == "" {
+		resp.ErrorInfo.TraceID = apix.GetTraceID(c)
+	}
+	respBytes, err := respCodec.Marshal(resp)
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		writeError(c, http.StatusInternalServerError, register.NewError(register.CodeInternal, err.Error()))
 		return
 	}
 
-	c.Set(responseLogKey, string(respBytes))
+	c.Set(responseLogKey, responseLogValue(respCodec, respBytes))
 
-	// 6. Always return 200; business errors stay in payload
-	c.Data(200, "application/json", respBytes)
+	// 9. Always return 200; business errors stay in payload
+	c.Data(200, respCodec.ContentType(), respBytes)
+}
+
+// applyDeadline sets the request context deadline the handler observes (the engine runs with
+// ContextWithFallback, so the gin.Context passed as ctx reports it too). It answers the call
+// itself and returns false when the budget is already spent or the header is malformed.
+func applyDeadline(c *gin.Context, timeout time.Duration) (context.CancelFunc, bool) {
+	deadline, ok, err := register.DeadlineFromHeader(c.Request.Header)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, register.NewError(register.CodeBadRequest, err.Error()))
+		return nil, false
+	}
+	if timeout > 0 {
+		if d := time.Now().Add(timeout); !ok || d.Before(deadline) {
+			deadline, ok = d, true
+		}
+	}
+	if !ok {
+		return func() {}, true
+	}
+	if !time.Now().Before(deadline) {
+		writeError(c, http.StatusGatewayTimeout, register.NewError(register.CodeDeadline, "deadline exceeded before the call started"))
+		return nil, false
+	}
+	ctx, cancel := context.WithDeadline(c.Request.Context(), deadline)
+	c.Request = c.Request.WithContext(ctx)
+	return cancel, true
+}
+
+// negotiateCodecs picks the request codec from Content-Type (JSON when absent or unknown)
+// and answers with the first codec named in Accept, else the request codec.
+func negotiateCodecs(c *gin.Context) (req, resp register.Codec) {
+	req, ok := register.CodecFor(c.GetHeader("Content-Type"))
+	if !ok {
+		req = register.JSONCodec
+	}
+	if resp, ok = register.CodecFor(c.GetHeader("Accept")); !ok {
+		resp = req
+	}
+	return req, resp
+}
+
+// responseLogValue keeps binary payloads out of the invoke log.
+func responseLogValue(codec register.Codec, b []byte) string {
+	if codec == register.JSONCodec {
+		return string(b)
+	}
+	return fmt.Sprintf("<%s %d bytes>", codec.Name(), len(b))
+}
+
+// writeError answers a call that failed outside the handler with the regular response envelope.
+func writeError(c *gin.Context, status int, e *register.Error) {
+	if e.TraceID == "" {
+		e.TraceID = apix.GetTraceID(c)
+	}
+	c.Set(callErrorKey, e.Error())
+	_, codec := negotiateCodecs(c)
+	respBytes, err := codec.Marshal(register.ErrorResponse(e))
+	if err != nil {
+		c.AbortWithStatusJSON(status, gin.H{"error": e.Error()})
+		return
+	}
+	c.Set(responseLogKey, responseLogValue(codec, respBytes))
+	c.Data(status, codec.ContentType(), respBytes)
+	c.Abort()
 }
@@ -0,0 +1,108 @@
+package gnhttp
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/jom-io/gorig-node/client/register"
+	"github.com/jom-io/gorig-node/internal/stats"
+	"net/http"
+	"sync"
+)
+
+// inflightTracker counts API calls in progress and refuses new ones once draining starts.
+type inflightTracker struct {
+	mu       sync.Mutex
+	n        int
+	draining bool
+	idle     chan struct{} // closed when draining and n reaches 0
+}
+
+var tracker = &inflightTracker{}
+
+func (t *inflightTracker) enter() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.draining {
+		return false
+	}
+	t.n++
+	return true
+}
+
+func (t *inflightTracker) leave() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.n--
+	if t.draining && t.n == 0 && t.idle != nil {
+		close(t.idle)
+		t.idle = nil
+	}
+}
+
+func (t *inflightTracker) startDrain() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.draining = true
+	stats.SetDraining(true)
+}
+
+// wait blocks until no call is in flight or ctx ends.
+func (t *inflightTracker) wait(ctx context.Context) error {
+	t.mu.Lock()
+	if t.n == 0 {
+		t.mu.Unlock()
+		return nil
+	}
+	if t.idle == nil {
+		t.idle = make(chan struct{})
+	}
+	idle := t.idle
+	t.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (t *inflightTracker) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.draining = false
+	stats.SetDraining(false)
+}
+
+// Draining reports whether the inbound server stopped taking new calls.
+func Draining() bool {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	return tracker.draining
+}
+
+// InFlight returns the number of API calls currently being served.
+func InFlight() int {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	return tracker.n
+}
+
+// Drain makes the node refuse new API calls with 503 so callers move on to other instances.
+// Calls already in progress keep running.
+func Drain() {
+	tracker.startDrain()
+}
+
+// trackInflight guards API routes: counts calls in progress and turns new ones away while draining.
+func trackInflight() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !tracker.enter() {
+			c.Header("Connection", "close")
+			writeError(c, http.StatusServiceUnavailable, register.NewError(register.CodeUnavailable, "node is draining").WithRetryable(true))
+			return
+		}
+		defer tracker.leave()
+		c.Next()
+	}
+}
@@ -6,13 +6,13 @@ import (
 	"github.com/jom-io/gorig-node/client/register"
 )
 
-func registerAllApisToRouter(router *gin.Engine) {
-	for name, srv := range register.RegisteredServers() {
+func registerApisToRouter(router *gin.Engine, servers map[register.ServerName]*register.ServerRegister) {
+	for name, srv := range servers {
 		srv := srv
 		for _, api := range srv.Apis {
 			api := api
 			path := fmt.Sprintf("/%s/%s", name, api.Method)
-			router.POST(path, func(c *gin.Context) {
+			router.POST(path, observeCall(name, api.Method), traceCall(name, api.Method), requireClientCert(), trackInflight(), func(c *gin.Context) {
 				handleAPIRequest(srv, api, c)
 			})
 		}
@@ -0,0 +1,42 @@
+package gnhttp
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jom-io/gorig-node/internal/metrics"
+	"github.com/jom-io/gorig-node/internal/stats"
+	"net/http"
+	"time"
+)
+
+// callErrorKey holds the error message of a call, whether returned by the handler
+// inside a 200 envelope or answered by the node with an error status.
+const callErrorKey = "gn_call_error"
+
+// observeCall records count, latency, outcome and in-flight calls of one API route,
+// both as metrics and as the load reported in heartbeats.
+// It runs before trackInflight so calls refused while draining count as transport errors.
+func observeCall(service, method string) gin.HandlerFunc {
+	inFlight := metrics.InFlight.WithLabelValues(service, method)
+	duration := metrics.CallDuration.WithLabelValues(service, method)
+	return func(c *gin.Context) {
+		start := time.Now()
+		inFlight.Inc()
+		stats.Enter(service)
+		defer func() {
+			elapsed := time.Since(start)
+			inFlight.Dec()
+			duration.Observe(elapsed.Seconds())
+			stats.Leave(service, elapsed, c.Writer.Status() != http.StatusOK)
+
+			outcome := metrics.OutcomeOK
+			switch {
+			case c.Writer.Status() != http.StatusOK:
+				outcome = metrics.OutcomeTransportError
+			case c.GetString(callErrorKey) != "":
+				outcome = metrics.OutcomeBusinessError
+			}
+			metrics.Calls.WithLabelValues(service, method, outcome).Inc()
+		}()
+		c.Next()
+	}
+}
@@ -0,0 +1,21 @@
+package gnhttp
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jom-io/gorig-node/gntrace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// traceCall starts the server span of an API call, continuing the caller's traceparent.
+// The span rides on the request context, so handlers find it with trace.SpanFromContext(ctx).
+func traceCall(service, method string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := gntrace.Extract(c.Request.Context(), c.Request.Header)
+		ctx, span := gntrace.StartCall(ctx, service, method, trace.SpanKindServer)
+		c.Request = c.Request.WithContext(ctx)
+		defer func() {
+			gntrace.EndCall(span, c.Writer.Status(), c.GetString(callErrorKey))
+		}()
+		c.Next()
+	}
+}
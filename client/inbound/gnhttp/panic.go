@@ -0,0 +1,38 @@
+package gnhttp
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/jom-io/gorig-node/client/register"
+	"github.com/jom-io/gorig-node/internal/metrics"
+	"github.com/jom-io/gorig/apix"
+	"github.com/jom-io/gorig/global/consts"
+	"go.uber.org/zap"
+	"net/http"
+	"reflect"
+	"runtime/debug"
+)
+
+// callHandler runs the handler through the service's interceptors and isolates their panics: the stack goes to the invoke log, the
+// counter is bumped and the caller gets a regular envelope instead of gin's bare 500.
+func callHandler(c *gin.Context, srv *register.ServerRegister, method string, args []reflect.Value) (results []reflect.Value, err error, ok bool) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		metrics.Panics.WithLabelValues(srv.ServiceName, method).Inc()
+
+		invokeLogger.Error("PANIC",
+			zap.String(consts.TraceIDKey, apix.GetTraceID(c)),
+			zap.String("service", srv.ServiceName),
+			zap.String("method", method),
+			zap.String("panic", fmt.Sprint(r)),
+			zap.ByteString("stack", debug.Stack()),
+		)
+		writeError(c, http.StatusInternalServerError, register.NewError(register.CodeInternal, "internal error").WithDetail("method", method))
+		results, err, ok = nil, nil, false
+	}()
+	results, err = srv.Invoke(c, method, args)
+	return results, err, true
+}
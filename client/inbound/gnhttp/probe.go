@@ -0,0 +1,56 @@
+package gnhttp
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jom-io/gorig-node/client/register"
+	"net/http"
+)
+
+// readiness is the /readyz answer; Ready holds only when every other field is clean.
+type readiness struct {
+	Ready        bool                           `json:"ready"`
+	Listening    bool                           `json:"listening"`
+	Draining     bool                           `json:"draining"`
+	Unregistered []register.ServerName          `json:"unregistered,omitempty"`
+	Failing      map[register.ServerName]string `json:"failing,omitempty"`
+}
+
+// Listening reports whether Start bound all inbound listeners and Shutdown has not run.
+func Listening() bool {
+	return listening.Load()
+}
+
+// registerProbeRoutes mounts the Kubernetes probes. /healthz only tells that the process
+// serves HTTP; /readyz turns green once every created service is registered with the hub,
+// the listeners are bound and the latest health checks passed, and red again as soon as draining starts.
+func registerProbeRoutes(router *gin.Engine) {
+	router.GET("/healthz", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+	router.GET("/readyz", func(c *gin.Context) {
+		r := readiness{
+			Listening:    Listening(),
+			Draining:     Draining(),
+			Unregistered: register.Unregistered(),
+		}
+		// cached results; the checks themselves run in the background
+		for name, srv := range register.RegisteredServers() {
+			if !srv.Status().Created {
+				continue
+			}
+			if err := srv.Health(); err != nil {
+				if r.Failing == nil {
+					r.Failing = map[register.ServerName]string{}
+				}
+				r.Failing[name] = err.Error()
+			}
+		}
+		r.Ready = r.Listening && !r.Draining && len(r.Unregistered) == 0 && len(r.Failing) == 0
+
+		status := http.StatusOK
+		if !r.Ready {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, r)
+	})
+}
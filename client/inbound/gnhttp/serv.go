@@ -1,52 +1,188 @@
 package gnhttp
 
 import (
+	"context"
 	"github.com/gin-contrib/gzip"
 	"github.com/gin-gonic/gin"
+	"github.com/jom-io/gorig-node/client/register"
+	"github.com/jom-io/gorig-node/gncfg"
+	"github.com/jom-io/gorig-node/internal/metrics"
 	"github.com/jom-io/gorig/httpx"
 	"github.com/jom-io/gorig/utils/errors"
 	"github.com/jom-io/gorig/utils/sys"
+	"net"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
-var gHttpServer *http.Server
+var (
+	serverMu     sync.Mutex
+	gHttpServers []*http.Server // node port first, then one per distinct service port
+	listening    atomic.Bool    // all listeners of Start are bound and serving
+)
 
-// NewEngine builds inbound HTTP engine for direct request simulation in tests.
+// NewEngine builds inbound HTTP engine for direct request simulation in tests. Like Start, it
+// makes the adaptive limiter learn from scratch.
 func NewEngine() *gin.Engine {
+	adaptive.reset()
+	return newEngine(register.RegisteredServers())
+}
+
+func newEngine(servers map[register.ServerName]*register.ServerRegister) *gin.Engine {
 	gEngine := gin.New()
+	// let handlers taking the gin.Context as ctx see the call deadline and cancellation
+	gEngine.ContextWithFallback = true
 
 	gEngine.Use(httpx.Recovery())
 	gEngine.Use(Logger())
 	gEngine.Use(httpx.CORS())
 	gEngine.Use(gzip.Gzip(gzip.DefaultCompression))
 
-	registerAllApisToRouter(gEngine)
+	gEngine.GET("/metrics", gin.WrapH(metrics.Handler()))
+	registerAdminRoutes(gEngine)
+	registerProbeRoutes(gEngine)
+	registerApisToRouter(gEngine, servers)
 	return gEngine
 }
 
+// Start listens on the node port for services without a distinct Host port, and on one
+// extra port per distinct Host port so the advertised address always has a listener.
+// Every listener serves https when gncfg has an inbound TLS certificate.
 func Start(port string) error {
-	if gHttpServer != nil {
+	serverMu.Lock()
+	defer serverMu.Unlock()
+	if len(gHttpServers) > 0 {
 		return nil
 	}
+	tracker.reset()
+	adaptive.reset()
+
+	tlsCfg, err := serverTLSConfig(gncfg.Current())
+	if err != nil {
+		sys.Error(" * gorig-node invoke http server tls config failed: ", err.Error())
+		return err
+	}
+
+	groups := listenGroups(port, register.RegisteredServers())
+	addrs := []string{port}
+	for addr := range groups {
+		if addr != port {
+			addrs = append(addrs, addr)
+		}
+	}
 
-	gHttpServer = &http.Server{
-		Addr:              port,
-		Handler:           NewEngine(),
-		ReadTimeout:       10 * time.Second,
-		ReadHeaderTimeout: 10 * time.Second,
-		WriteTimeout:      120 * time.Second,
-		IdleTimeout:       120 * time.Second,
+	// Bind every address before serving so a taken port fails Start and readiness
+	// only turns green once all advertised addresses accept connections.
+	listeners := make([]net.Listener, 0, len(addrs))
+	for _, addr := range addrs {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+			sys.Error(" * gorig-node invoke http server failed: ", addr, " ", err.Error())
+			return err
+		}
+		listeners = append(listeners, ln)
 	}
 
-	go func() {
-		err := gHttpServer.ListenAndServe()
-		if err != nil && err != http.ErrServerClosed {
-			sys.Error(" * gorig-node invoke http server failed: ", err.Error())
-			sys.Exit(errors.Sys(err.Error()))
-			return
+	for i, addr := range addrs {
+		// No WriteTimeout: it would cut off responses of calls running under a longer Timeout,
+		// MethodTimeout or caller deadline. Handlers are bounded by the per-call deadline instead.
+		srv := &http.Server{
+			Addr:              addr,
+			Handler:           newEngine(groups[addr]),
+			ReadTimeout:       10 * time.Second,
+			ReadHeaderTimeout: 10 * time.Second,
+			IdleTimeout:       120 * time.Second,
+			TLSConfig:         tlsCfg,
+		}
+		gHttpServers = append(gHttpServers, srv)
+
+		ln := listeners[i]
+		go func() {
+			var err error
+			if tlsCfg != nil {
+				err = srv.ServeTLS(ln, "", "") // the certificate comes from TLSConfig.GetCertificate
+			} else {
+				err = srv.Serve(ln)
+			}
+			if err != nil && err != http.ErrServerClosed {
+				sys.Error(" * gorig-node invoke http server failed: ", srv.Addr, " ", err.Error())
+				sys.Exit(errors.Sys(err.Error()))
+				return
+			}
+		}()
+		if addr != port {
+			sys.Info(" * gorig-node invoke http server for service port on: ", addr)
 		}
-	}()
+	}
+	listening.Store(true)
 
 	return nil
 }
+
+// listenGroups buckets services by listen address. A service whose Host carries a port
+// other than the node port gets its own ":port" bucket; all others share nodePort.
+func listenGroups(nodePort string, servers map[register.ServerName]*register.ServerRegister) map[string]map[register.ServerName]*register.ServerRegister {
+	_, nodePortNum, err := net.SplitHostPort(nodePort)
+	if err != nil {
+		nodePortNum = ""
+	}
+
+	groups := map[string]map[register.ServerName]*register.ServerRegister{
+		nodePort: {},
+	}
+	for name, srv := range servers {
+		addr := nodePort
+		if _, p, err := net.SplitHostPort(srv.Host); err == nil && p != "" && p != nodePortNum {
+			addr = ":" + p
+		}
+		if groups[addr] == nil {
+			groups[addr] = map[register.ServerName]*register.ServerRegister{}
+		}
+		groups[addr][name] = srv
+	}
+	return groups
+}
+
+// Shutdown drains the inbound server: new calls are refused, calls in flight are
+// awaited until ctx ends, then listeners and connections are closed.
+func Shutdown(ctx context.Context) error {
+	Drain()
+
+	serverMu.Lock()
+	servers := gHttpServers
+	gHttpServers = nil
+	listening.Store(false)
+	serverMu.Unlock()
+	if len(servers) == 0 {
+		return nil
+	}
+
+	for _, srv := range servers {
+		srv.SetKeepAlivesEnabled(false)
+	}
+	waitErr := tracker.wait(ctx)
+	if waitErr != nil {
+		sys.Error(" * gorig-node invoke http server drain timeout, in flight: ", InFlight())
+	}
+
+	var firstErr error
+	for _, srv := range servers {
+		if err := srv.Shutdown(ctx); err != nil {
+			_ = srv.Close()
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	// with the listeners gone nothing is left to turn away; a later Start serves again
+	tracker.reset()
+	if firstErr != nil {
+		return firstErr
+	}
+	return waitErr
+}
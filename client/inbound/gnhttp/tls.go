@@ -0,0 +1,176 @@
+package gnhttp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/jom-io/gorig-node/client/register"
+	"github.com/jom-io/gorig-node/gncfg"
+	"github.com/jom-io/gorig/utils/sys"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// certReloader serves a key pair from disk and loads it again once either file changes,
+// so rotated certificates are picked up by new connections without a restart.
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time // newest modification time of the files when cert was loaded
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := r.GetCertificate(nil); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate. A pair that fails to load, e.g.
+// while only one of the files has been replaced, keeps the previous certificate in use.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	modTime, err := r.latestModTime()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cert != nil && (err != nil || !modTime.After(r.modTime)) {
+		return r.cert, nil
+	}
+	cert, loadErr := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if loadErr != nil {
+		if r.cert != nil {
+			sys.Error(" * gorig-node reload tls certificate failed, keep the current one: ", loadErr.Error())
+			r.modTime = modTime
+			return r.cert, nil
+		}
+		return nil, fmt.Errorf("load tls certificate: %w", loadErr)
+	}
+	if r.cert != nil {
+		sys.Info(" * gorig-node tls certificate reloaded: ", r.certFile)
+	}
+	r.cert = &cert
+	r.modTime = modTime
+	return r.cert, nil
+}
+
+func (r *certReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, name := range []string{r.certFile, r.keyFile} {
+		fi, err := os.Stat(name)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// caReloader serves the client CA pool from disk and loads it again once the bundle changes.
+type caReloader struct {
+	file string
+	base *tls.Config
+
+	mu      sync.Mutex
+	cfg     *tls.Config // base carrying the current pool
+	modTime time.Time
+}
+
+func newCAReloader(file string, base *tls.Config) (*caReloader, error) {
+	r := &caReloader{file: file, base: base}
+	if _, err := r.GetConfigForClient(nil); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetConfigForClient implements tls.Config.GetConfigForClient. A bundle that fails to load
+// keeps the previous pool in use.
+func (r *caReloader) GetConfigForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	fi, statErr := os.Stat(r.file)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cfg != nil && (statErr != nil || !fi.ModTime().After(r.modTime)) {
+		return r.cfg, nil
+	}
+	pool, err := loadCAPool(r.file)
+	if err != nil {
+		if r.cfg != nil {
+			sys.Error(" * gorig-node reload tls client CA failed, keep the current one: ", err.Error())
+			r.modTime = fi.ModTime()
+			return r.cfg, nil
+		}
+		return nil, err
+	}
+	if r.cfg != nil {
+		sys.Info(" * gorig-node tls client CA reloaded: ", r.file)
+	}
+	cfg := r.base.Clone()
+	cfg.ClientCAs = pool
+	r.cfg = cfg
+	r.modTime = fi.ModTime()
+	return r.cfg, nil
+}
+
+func loadCAPool(file string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("load tls client CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("load tls client CA: no certificate found in %s", file)
+	}
+	return pool, nil
+}
+
+// serverTLSConfig builds the inbound TLS config of cfg, or nil when the node serves plain http.
+// With a client CA the handshake only verifies certificates callers choose to send, so probes
+// and metrics scrapes without one still connect; requireClientCert enforces them on API routes.
+// The certificate, key and CA bundle are all reloaded when their files change.
+func serverTLSConfig(cfg gncfg.GlobalConfig) (*tls.Config, error) {
+	if !cfg.InboundTLS() {
+		return nil, nil
+	}
+	reloader, err := newCertReloader(cfg.TLSCertFile, cfg.TLSKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	tlsCfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+	if cfg.TLSMinVersion == "1.3" {
+		tlsCfg.MinVersion = tls.VersionTLS13
+	}
+	if cfg.TLSClientCAFile != "" {
+		tlsCfg.ClientAuth = tls.VerifyClientCertIfGiven
+		cas, err := newCAReloader(cfg.TLSClientCAFile, tlsCfg.Clone())
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.GetConfigForClient = cas.GetConfigForClient
+	}
+	return tlsCfg, nil
+}
+
+// requireClientCert guards API and admin routes when a client CA is configured: calls arriving over TLS
+// must present a certificate the handshake verified.
+func requireClientCert() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.TLS != nil && gncfg.Current().TLSClientCAFile != "" && len(c.Request.TLS.VerifiedChains) == 0 {
+			writeError(c, http.StatusUnauthorized, register.NewError(register.CodeUnauthenticated, "client certificate required"))
+			return
+		}
+		c.Next()
+	}
+}
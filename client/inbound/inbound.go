@@ -1,13 +1,32 @@
 package inbound
 
 import (
+	"context"
 	"github.com/jom-io/gorig-node/client/inbound/gnhttp"
+	"time"
 )
 
+// defaultStopTimeout bounds how long StopInbound waits for calls in flight.
+const defaultStopTimeout = 30 * time.Second
+
 func StartInbound(addr string) error {
 	return gnhttp.Start(addr)
 }
 
+// DrainInbound refuses new calls while those in flight keep running.
+func DrainInbound() {
+	gnhttp.Drain()
+}
+
+// StopInbound drains the inbound server, waiting up to 30s for calls in flight.
+// Use StopInboundContext to choose the wait.
 func StopInbound() error {
-	return nil
+	ctx, cancel := context.WithTimeout(context.Background(), defaultStopTimeout)
+	defer cancel()
+	return StopInboundContext(ctx)
+}
+
+// StopInboundContext waits for calls in flight until ctx ends, then closes the inbound server.
+func StopInboundContext(ctx context.Context) error {
+	return gnhttp.Shutdown(ctx)
 }
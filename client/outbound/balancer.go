@@ -0,0 +1,257 @@
+package outbound
+
+import (
+	"context"
+	"fmt"
+	"github.com/jom-io/gorig-node/client/register"
+	"hash/crc32"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Balancer picks the instance that serves a call.
+type Balancer interface {
+	// Pick chooses one of instances (never empty). done is called once the call finishes.
+	Pick(ctx context.Context, instances []register.Instance) (ins register.Instance, done func())
+}
+
+type hashKeyCtx struct{}
+
+// WithHashKey attaches the request key used by ConsistentHash to pick an instance.
+func WithHashKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, hashKeyCtx{}, key)
+}
+
+func hashKeyFrom(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(hashKeyCtx{}).(string)
+	return key, ok && key != ""
+}
+
+type resolvedCtx struct{}
+
+// WithResolved attaches the full instance set a call resolved to. On retries Pick only gets the
+// instances not tried yet; balancers that keep a stable mapping (ConsistentHash) use this set so
+// excluding one instance does not reshuffle the others.
+func WithResolved(ctx context.Context, instances []register.Instance) context.Context {
+	return context.WithValue(ctx, resolvedCtx{}, instances)
+}
+
+func resolvedFrom(ctx context.Context, candidates []register.Instance) []register.Instance {
+	if all, ok := ctx.Value(resolvedCtx{}).([]register.Instance); ok && len(all) >= len(candidates) {
+		return all
+	}
+	return candidates
+}
+
+func noop() {}
+
+// ---------------------------
+// Round robin
+// ---------------------------
+
+type roundRobin struct {
+	next atomic.Uint64
+}
+
+// RoundRobin cycles through instances in order.
+func RoundRobin() Balancer {
+	return &roundRobin{}
+}
+
+func (b *roundRobin) Pick(_ context.Context, instances []register.Instance) (register.Instance, func()) {
+	n := b.next.Add(1) - 1
+	return instances[n%uint64(len(instances))], noop
+}
+
+// ---------------------------
+// Random
+// ---------------------------
+
+type random struct{}
+
+// Random picks a uniformly random instance.
+func Random() Balancer {
+	return random{}
+}
+
+func (random) Pick(_ context.Context, instances []register.Instance) (register.Instance, func()) {
+	return instances[rand.Intn(len(instances))], noop
+}
+
+// ---------------------------
+// Weighted (smooth weighted round robin)
+// ---------------------------
+
+type weighted struct {
+	mu      sync.Mutex
+	current map[string]int
+}
+
+// Weighted spreads calls in proportion to Instance.Weight, interleaving
+// instances smoothly instead of sending bursts to the heaviest one.
+func Weighted() Balancer {
+	return &weighted{current: map[string]int{}}
+}
+
+func (b *weighted) Pick(_ context.Context, instances []register.Instance) (register.Instance, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// drop state of instances that are gone
+	if len(b.current) > 2*len(instances) {
+		b.current = map[string]int{}
+	}
+
+	total := 0
+	best := -1
+	for i, ins := range instances {
+		w := instanceWeight(ins)
+		total += w
+		b.current[ins.Host] += w
+		if best < 0 || b.current[ins.Host] > b.current[instances[best].Host] {
+			best = i
+		}
+	}
+	b.current[instances[best].Host] -= total
+	return instances[best], noop
+}
+
+func instanceWeight(ins register.Instance) int {
+	if ins.Weight <= 0 {
+		return 1
+	}
+	return ins.Weight
+}
+
+// ---------------------------
+// Least in-flight
+// ---------------------------
+
+type leastInFlight struct {
+	mu       sync.Mutex
+	inflight map[string]int
+	next     uint64
+}
+
+// LeastInFlight picks the instance with the fewest calls currently in progress from this process.
+func LeastInFlight() Balancer {
+	return &leastInFlight{inflight: map[string]int{}}
+}
+
+func (b *leastInFlight) Pick(_ context.Context, instances []register.Instance) (register.Instance, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// rotate the starting point so ties do not always land on the first instance
+	start := int(b.next % uint64(len(instances)))
+	b.next++
+
+	best := start
+	for i := 1; i < len(instances); i++ {
+		idx := (start + i) % len(instances)
+		if b.inflight[instances[idx].Host] < b.inflight[instances[best].Host] {
+			best = idx
+		}
+	}
+
+	host := instances[best].Host
+	b.inflight[host]++
+
+	var once sync.Once
+	return instances[best], func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if b.inflight[host]--; b.inflight[host] <= 0 {
+				delete(b.inflight, host)
+			}
+		})
+	}
+}
+
+// ---------------------------
+// Consistent hash
+// ---------------------------
+
+const defaultHashReplicas = 100
+
+type hashRing struct {
+	hashes []uint32
+	hosts  map[uint32]string // hash -> instance host
+}
+
+type consistentHash struct {
+	replicas int
+	fallback Balancer
+
+	mu      sync.Mutex
+	ringKey string
+	ring    *hashRing
+}
+
+// ConsistentHash routes calls carrying the same key (see WithHashKey) to the same instance,
+// moving only a small share of keys when instances come and go. The ring is built from the
+// full resolved set (see WithResolved); an instance excluded on retry is skipped clockwise, so
+// only its own keys move. Calls without a key fall back to round robin.
+func ConsistentHash() Balancer {
+	return &consistentHash{replicas: defaultHashReplicas, fallback: RoundRobin()}
+}
+
+func (b *consistentHash) Pick(ctx context.Context, instances []register.Instance) (register.Instance, func()) {
+	key, ok := hashKeyFrom(ctx)
+	if !ok {
+		return b.fallback.Pick(ctx, instances)
+	}
+
+	candidates := make(map[string]int, len(instances))
+	for i, ins := range instances {
+		candidates[ins.Host] = i
+	}
+
+	ring := b.ringFor(resolvedFrom(ctx, instances))
+	h := crc32.ChecksumIEEE([]byte(key))
+	start := sort.Search(len(ring.hashes), func(i int) bool { return ring.hashes[i] >= h })
+	for n := 0; n < len(ring.hashes); n++ {
+		host := ring.hosts[ring.hashes[(start+n)%len(ring.hashes)]]
+		if idx, ok := candidates[host]; ok {
+			return instances[idx], noop
+		}
+	}
+	// none of the candidates is on the ring (the resolved set changed under us)
+	return b.fallback.Pick(ctx, instances)
+}
+
+// ringFor returns the ring for instances, rebuilding it only when the host set changes.
+func (b *consistentHash) ringFor(instances []register.Instance) *hashRing {
+	hosts := make([]string, len(instances))
+	for i, ins := range instances {
+		hosts[i] = ins.Host
+	}
+	ringKey := strings.Join(hosts, ",")
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.ring != nil && b.ringKey == ringKey {
+		return b.ring
+	}
+
+	ring := &hashRing{hosts: map[uint32]string{}}
+	for _, host := range hosts {
+		for r := 0; r < b.replicas; r++ {
+			h := crc32.ChecksumIEEE([]byte(fmt.Sprintf("%s#%d", host, r)))
+			if _, dup := ring.hosts[h]; dup {
+				continue
+			}
+			ring.hosts[h] = host
+			ring.hashes = append(ring.hashes, h)
+		}
+	}
+	sort.Slice(ring.hashes, func(i, j int) bool { return ring.hashes[i] < ring.hashes[j] })
+
+	b.ring = ring
+	b.ringKey = ringKey
+	return ring
+}
@@ -0,0 +1,183 @@
+package outbound
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jom-io/gorig-node/client/register"
+	"github.com/jom-io/gorig-node/gntrace"
+	"github.com/jom-io/gorig/utils/logger"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// invoke packs args, posts them to an instance of the service and unpacks the response.
+func (c *ServiceClient) invoke(ctx context.Context, method string, meta register.MethodMeta, args []reflect.Value) ([]reflect.Value, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultCallTimeout)
+		defer cancel()
+	}
+
+	body, err := register.PackRequestWith(c.codec, meta, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s.%s pack request failed: %w", c.service, method, err)
+	}
+
+	instances, err := register.ResolveContext(ctx, c.service, c.env, c.version)
+	if err != nil {
+		return nil, fmt.Errorf("%s.%s resolve failed: %w", c.service, method, err)
+	}
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("%s.%s: %w", c.service, method, ErrNoInstance)
+	}
+
+	// Retry the remaining instances while the failure leaves the call unexecuted; the balancer
+	// picks among them each time.
+	var lastErr error
+	ctx = WithResolved(ctx, instances)
+	for remaining := instances; len(remaining) > 0; {
+		ins, done := c.balancer.Pick(ctx, remaining)
+		respBody, err := c.post(ctx, ins, method, body)
+		done()
+		if err != nil {
+			if !retryable(err) {
+				return nil, fmt.Errorf("%s.%s call failed: %w", c.service, method, err)
+			}
+			lastErr = err
+			if ctx.Err() != nil {
+				break
+			}
+			remaining = without(remaining, ins.Host)
+			continue
+		}
+		outs, err := register.UnpackResponseWith(c.codec, meta, respBody)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s unpack response failed: %w", c.service, method, err)
+		}
+		return outs, nil
+	}
+	return nil, fmt.Errorf("%s.%s call failed: %w", c.service, method, lastErr)
+}
+
+// post sends one attempt under its own client span, whose traceparent the instance continues.
+func (c *ServiceClient) post(ctx context.Context, ins register.Instance, method string, body []byte) (respBody []byte, err error) {
+	ctx, span := gntrace.StartCall(ctx, c.service, method, trace.SpanKindClient)
+	status := 0
+	defer func() {
+		errMsg := ""
+		if err != nil {
+			errMsg = err.Error()
+		}
+		gntrace.EndCall(span, status, errMsg)
+	}()
+
+	url := buildCallURL(ins, c.service, method)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range c.header {
+		req.Header[k] = v
+	}
+	gntrace.Inject(ctx, req.Header)
+	req.Header.Set("Content-Type", c.codec.ContentType())
+	req.Header.Set("Accept", c.codec.ContentType())
+	register.SetDeadlineHeader(req.Header, ctx)
+	if traceID := logger.GetTraceID(ctx); traceID != "" {
+		req.Header.Set("X-Request-ID", traceID)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	status = resp.StatusCode
+
+	respBody, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		se := &statusError{URL: url, Status: resp.StatusCode, Message: strings.TrimSpace(string(respBody))}
+		// error answers may come from a proxy or an older node, so decode by their own Content-Type
+		codec, ok := register.CodecFor(resp.Header.Get("Content-Type"))
+		if !ok {
+			codec = register.JSONCodec
+		}
+		var w register.WrappedResponse
+		if codec.Unmarshal(respBody, &w) == nil && w.Error != "" {
+			se.Message = w.Error
+			se.Remote = w.ErrorInfo
+		}
+		return nil, se
+	}
+	return respBody, nil
+}
+
+// statusError is a non-200 answer from an instance. Remote holds the node's structured error, if any.
+type statusError struct {
+	URL     string
+	Status  int
+	Message string
+	Remote  *register.Error
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("request %s failed with status %d: %s", e.URL, e.Status, e.Message)
+}
+
+func (e *statusError) Unwrap() error {
+	if e.Remote == nil {
+		return nil
+	}
+	return e.Remote
+}
+
+// retryable reports whether another instance may succeed (e.g. this one is draining).
+func (e *statusError) retryable() bool {
+	return e.Status == http.StatusServiceUnavailable || (e.Remote != nil && e.Remote.Retryable)
+}
+
+// retryable reports whether another instance may take a call that failed with err. Only
+// refused connections and answers the node marks retryable qualify: after a timeout or a cut
+// response the handler may already have run, and methods need not be idempotent.
+func retryable(err error) bool {
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.retryable()
+	}
+	var op *net.OpError
+	return errors.As(err, &op) && op.Op == "dial"
+}
+
+func without(instances []register.Instance, host string) []register.Instance {
+	out := make([]register.Instance, 0, len(instances))
+	for _, ins := range instances {
+		if ins.Host != host {
+			out = append(out, ins)
+		}
+	}
+	return out
+}
+
+func buildCallURL(ins register.Instance, service, method string) string {
+	host := ins.Host
+	if !strings.Contains(host, "://") {
+		scheme := ins.Scheme
+		if scheme == "" {
+			scheme = "http"
+		}
+		host = scheme + "://" + host
+	}
+	return strings.TrimRight(host, "/") + "/" + service + "/" + method
+}
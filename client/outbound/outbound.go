@@ -0,0 +1,194 @@
+package outbound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jom-io/gorig-node/client/register"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+var (
+	// ErrNoInstance is returned when the hub reports no instance for a service.
+	ErrNoInstance = errors.New("no available instance")
+
+	defaultCallTimeout = 30 * time.Second
+	defaultHTTPClient  = &http.Client{}
+
+	ctxType   = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// ServiceClient calls the methods of one remote service through the hub.
+type ServiceClient struct {
+	service    string
+	env        string
+	version    string
+	httpClient *http.Client
+	header     http.Header
+	balancer   Balancer
+	codec      register.Codec
+}
+
+// Service("user") returns a client for the remote service registered under name.
+func Service(name string) *ServiceClient {
+	return &ServiceClient{
+		service:    name,
+		httpClient: defaultHTTPClient,
+		balancer:   RoundRobin(),
+		codec:      register.JSONCodec,
+	}
+}
+
+// Env selects instances registered with the given environment.
+func (c *ServiceClient) Env(env string) *ServiceClient {
+	c.env = env
+	return c
+}
+
+// Version selects instances registered with the given version.
+func (c *ServiceClient) Version(v string) *ServiceClient {
+	c.version = v
+	return c
+}
+
+// HTTPClient overrides the http.Client used to reach instances.
+func (c *ServiceClient) HTTPClient(hc *http.Client) *ServiceClient {
+	if hc != nil {
+		c.httpClient = hc
+	}
+	return c
+}
+
+// Header adds a header sent with every call, e.g. register.HeaderAPIKey or an Authorization
+// bearer token the remote node authenticates.
+func (c *ServiceClient) Header(key, value string) *ServiceClient {
+	if c.header == nil {
+		c.header = http.Header{}
+	}
+	c.header.Add(key, value)
+	return c
+}
+
+// Balancer selects how calls are spread across the resolved instances (RoundRobin by default).
+func (c *ServiceClient) Balancer(b Balancer) *ServiceClient {
+	if b != nil {
+		c.balancer = b
+	}
+	return c
+}
+
+// Codec selects the wire format for calls, e.g. register.MsgpackCodec (JSON by default).
+func (c *ServiceClient) Codec(codec register.Codec) *ServiceClient {
+	if codec != nil {
+		c.codec = codec
+	}
+	return c
+}
+
+// Bind points fnPtr (a pointer to a func variable) at the remote method, e.g.
+//
+//	var login func(ctx context.Context, req LoginReq) (LoginResp, error)
+//	err := outbound.Service("UserSample").Bind("Login", &login)
+//
+// The func must take context.Context first and return error last; transport
+// failures and remote business errors are both reported through that error.
+func (c *ServiceClient) Bind(method string, fnPtr interface{}) error {
+	pv := reflect.ValueOf(fnPtr)
+	if pv.Kind() != reflect.Ptr || pv.IsNil() || pv.Elem().Kind() != reflect.Func {
+		return fmt.Errorf("%s.%s bind target must be a pointer to a func", c.service, method)
+	}
+	fnType := pv.Elem().Type()
+
+	if fnType.NumIn() == 0 || fnType.In(0) != ctxType {
+		return fmt.Errorf("%s.%s bind target must take context.Context as the first parameter", c.service, method)
+	}
+	if fnType.NumOut() == 0 || fnType.Out(fnType.NumOut()-1) != errorType {
+		return fmt.Errorf("%s.%s bind target must return error as the last value", c.service, method)
+	}
+
+	meta, err := register.MetaOf(c.service, method, reflect.Zero(fnType).Interface())
+	if err != nil {
+		return err
+	}
+
+	stub := reflect.MakeFunc(fnType, func(in []reflect.Value) []reflect.Value {
+		ctx, _ := in[0].Interface().(context.Context)
+		outs, err := c.invoke(ctx, method, meta, in[1:])
+		if err != nil {
+			return zeroResults(fnType, err)
+		}
+		return normalizeResults(fnType, outs)
+	})
+	pv.Elem().Set(stub)
+	return nil
+}
+
+// Call invokes method with args and decodes the return values into results,
+// which must be pointers in the order the remote method returns them
+// (excluding its error). The remote error, if any, is returned.
+func (c *ServiceClient) Call(ctx context.Context, method string, args []interface{}, results ...interface{}) error {
+	meta := register.MethodMeta{HasCtx: true, CtxType: ctxType}
+	meta.InTypes = append(meta.InTypes, ctxType)
+
+	in := make([]reflect.Value, 0, len(args))
+	for i, arg := range args {
+		if arg == nil {
+			return fmt.Errorf("%s.%s arg %d must not be untyped nil", c.service, method, i)
+		}
+		v := reflect.ValueOf(arg)
+		in = append(in, v)
+		meta.InTypes = append(meta.InTypes, v.Type())
+	}
+
+	for i, res := range results {
+		rv := reflect.ValueOf(res)
+		if rv.Kind() != reflect.Ptr || rv.IsNil() {
+			return fmt.Errorf("%s.%s result %d must be a non-nil pointer", c.service, method, i)
+		}
+		meta.OutTypes = append(meta.OutTypes, rv.Elem().Type())
+	}
+	meta.OutTypes = append(meta.OutTypes, errorType)
+
+	outs, err := c.invoke(ctx, method, meta, in)
+	if err != nil {
+		return err
+	}
+	for i, res := range results {
+		reflect.ValueOf(res).Elem().Set(outs[i])
+	}
+	if errVal := outs[len(outs)-1]; !errVal.IsNil() {
+		return errVal.Interface().(error)
+	}
+	return nil
+}
+
+// zeroResults builds the return values of a stub that failed before reaching the remote method.
+func zeroResults(fnType reflect.Type, err error) []reflect.Value {
+	outs := make([]reflect.Value, fnType.NumOut())
+	for i := 0; i < len(outs)-1; i++ {
+		outs[i] = reflect.Zero(fnType.Out(i))
+	}
+	errVal := reflect.New(errorType).Elem()
+	errVal.Set(reflect.ValueOf(err))
+	outs[len(outs)-1] = errVal
+	return outs
+}
+
+// normalizeResults converts unpacked values to the exact declared types, as MakeFunc requires.
+func normalizeResults(fnType reflect.Type, outs []reflect.Value) []reflect.Value {
+	for i, v := range outs {
+		t := fnType.Out(i)
+		if v.Type() == t {
+			continue
+		}
+		nv := reflect.New(t).Elem()
+		if v.IsValid() && !(v.Kind() == reflect.Interface && v.IsNil()) {
+			nv.Set(v)
+		}
+		outs[i] = nv
+	}
+	return outs
+}
@@ -0,0 +1,266 @@
+package register
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/goccy/go-json"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HeaderAPIKey carries the key checked by APIKeyAuth.
+const HeaderAPIKey = "X-Api-Key"
+
+// AllowAny in an Allow list admits every authenticated caller.
+const AllowAny = "*"
+
+// Caller is the authenticated identity behind an inbound call.
+type Caller struct {
+	ID     string                 // e.g. "order-svc"; matched against Allow lists
+	Via    string                 // how it was established: "api_key", "jwt" or "mtls"
+	Claims map[string]interface{} // JWT claims, when Via is "jwt"
+}
+
+// Authenticator establishes the caller of an inbound request. It returns a nil Caller and nil
+// error when the request carries none of its credentials, so the next one can try, and an
+// error when credentials are present but invalid.
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Caller, error)
+}
+
+// AuthenticatorFunc adapts a function to Authenticator.
+type AuthenticatorFunc func(r *http.Request) (*Caller, error)
+
+func (f AuthenticatorFunc) Authenticate(r *http.Request) (*Caller, error) {
+	return f(r)
+}
+
+var (
+	authMu         sync.RWMutex
+	authenticators []Authenticator
+)
+
+// UseAuthenticator adds authenticators tried in order on every inbound call; the first one
+// recognising the request decides. Without any, calls stay anonymous.
+func UseAuthenticator(auth ...Authenticator) {
+	authMu.Lock()
+	defer authMu.Unlock()
+	authenticators = append(authenticators, auth...)
+}
+
+// Authenticate runs the configured authenticators against r. A nil Caller means anonymous.
+func Authenticate(r *http.Request) (*Caller, error) {
+	authMu.RLock()
+	chain := authenticators
+	authMu.RUnlock()
+	for _, a := range chain {
+		caller, err := a.Authenticate(r)
+		if err != nil || caller != nil {
+			return caller, err
+		}
+	}
+	return nil, nil
+}
+
+type callerKey struct{}
+
+// WithCaller returns ctx carrying caller, see CallerFrom.
+func WithCaller(ctx context.Context, caller *Caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFrom returns the authenticated caller of the call ctx belongs to.
+func CallerFrom(ctx context.Context) (*Caller, bool) {
+	caller, ok := ctx.Value(callerKey{}).(*Caller)
+	return caller, ok && caller != nil
+}
+
+// Allow("order-svc", "admin-svc") restricts every method of the service without its own list
+// to the given caller IDs; AllowAny admits any authenticated caller. Methods without any list
+// stay open to anonymous callers. Allow always sets the service list, wherever it appears in
+// the chain, and may be called once per service; MethodAllow is the per-method form.
+func (s *ServerCreator) Allow(callers ...string) *ServerCreator {
+	if s.srv == nil {
+		return s
+	}
+	if s.srv.allow != nil {
+		s.error = fmt.Errorf("set allow list: %s already has a service list, use MethodAllow for one method", s.srv.ServiceName)
+		return s
+	}
+	s.srv.allow = append([]string{}, callers...) // Allow() with no callers admits nobody
+	return s
+}
+
+// MethodAllow("Cancel", "order-svc") restricts one registered method to the given caller IDs,
+// overriding the service list set by Allow.
+func (s *ServerCreator) MethodAllow(name string, callers ...string) *ServerCreator {
+	meta, ok := s.srv.MethodMeta[name]
+	if !ok {
+		s.error = fmt.Errorf("set allow list: method %s not registered on %s", name, s.srv.ServiceName)
+		return s
+	}
+	meta.Allow = append([]string{}, callers...)
+	s.srv.MethodMeta[name] = meta
+	return s
+}
+
+// Authorize checks caller against the Allow list of method. It returns a CodeUnauthenticated
+// error when the method is restricted and caller is nil, CodePermissionDenied when caller is
+// not listed, and nil when the method is open or caller is admitted.
+func (s *ServerRegister) Authorize(method string, caller *Caller) *Error {
+	allow := s.MethodMeta[method].Allow
+	if allow == nil {
+		allow = s.allow
+	}
+	if allow == nil {
+		return nil
+	}
+	if caller == nil {
+		return NewError(CodeUnauthenticated, "authentication required").
+			WithDetail("service", s.ServiceName).WithDetail("method", method)
+	}
+	if slices.Contains(allow, AllowAny) || slices.Contains(allow, caller.ID) {
+		return nil
+	}
+	return Errorf(CodePermissionDenied, "caller %s may not call %s.%s", caller.ID, s.ServiceName, method).
+		WithDetail("service", s.ServiceName).WithDetail("method", method).WithDetail("caller", caller.ID)
+}
+
+var (
+	adminMu    sync.RWMutex
+	adminAllow []string
+)
+
+// UseAdminAllow restricts the /_gn introspection endpoints, which list every service, API and
+// hub of the node, to the given caller IDs (AllowAny admits any authenticated caller). They are
+// open to anonymous callers until this is called; UseAdminAllow() with no callers opens them again.
+func UseAdminAllow(callers ...string) {
+	adminMu.Lock()
+	defer adminMu.Unlock()
+	if len(callers) == 0 {
+		adminAllow = nil
+		return
+	}
+	adminAllow = append([]string{}, callers...)
+}
+
+// AuthorizeAdmin checks caller against the list set by UseAdminAllow, like Authorize.
+func AuthorizeAdmin(caller *Caller) *Error {
+	adminMu.RLock()
+	allow := adminAllow
+	adminMu.RUnlock()
+	if allow == nil {
+		return nil
+	}
+	if caller == nil {
+		return NewError(CodeUnauthenticated, "authentication required")
+	}
+	if slices.Contains(allow, AllowAny) || slices.Contains(allow, caller.ID) {
+		return nil
+	}
+	return Errorf(CodePermissionDenied, "caller %s may not use the admin endpoints", caller.ID).
+		WithDetail("caller", caller.ID)
+}
+
+// APIKeyAuth authenticates requests by their X-Api-Key header; keys maps each key to the
+// caller ID it stands for.
+func APIKeyAuth(keys map[string]string) Authenticator {
+	return AuthenticatorFunc(func(r *http.Request) (*Caller, error) {
+		key := r.Header.Get(HeaderAPIKey)
+		if key == "" {
+			return nil, nil
+		}
+		for k, id := range keys {
+			if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+				return &Caller{ID: id, Via: "api_key"}, nil
+			}
+		}
+		return nil, errors.New("unknown api key")
+	})
+}
+
+// JWTAuth verifies HS256 bearer tokens signed with secret. The caller ID is the "sub" claim;
+// "exp" and "nbf" are enforced when present. Bearer values that are not JWTs are ignored.
+func JWTAuth(secret []byte) Authenticator {
+	return AuthenticatorFunc(func(r *http.Request) (*Caller, error) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || strings.Count(token, ".") != 2 {
+			return nil, nil
+		}
+		parts := strings.Split(token, ".")
+
+		var header struct {
+			Alg string `json:"alg"`
+		}
+		if err := decodeJWTPart(parts[0], &header); err != nil {
+			return nil, err
+		}
+		if header.Alg != "HS256" {
+			return nil, errors.New("unsupported jwt alg " + header.Alg)
+		}
+		sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+		if err != nil {
+			return nil, errors.New("malformed jwt signature")
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(parts[0] + "." + parts[1]))
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return nil, errors.New("invalid jwt signature")
+		}
+
+		var claims map[string]interface{}
+		if err := decodeJWTPart(parts[1], &claims); err != nil {
+			return nil, err
+		}
+		now := float64(time.Now().Unix())
+		if exp, ok := claims["exp"].(float64); ok && now >= exp {
+			return nil, errors.New("jwt expired")
+		}
+		if nbf, ok := claims["nbf"].(float64); ok && now < nbf {
+			return nil, errors.New("jwt not valid yet")
+		}
+		sub, _ := claims["sub"].(string)
+		if sub == "" {
+			return nil, errors.New("jwt has no sub claim")
+		}
+		return &Caller{ID: sub, Via: "jwt", Claims: claims}, nil
+	})
+}
+
+func decodeJWTPart(part string, out interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return errors.New("malformed jwt")
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		return errors.New("malformed jwt")
+	}
+	return nil
+}
+
+// MTLSAuth identifies callers by the verified client certificate of the TLS connection,
+// using its common name or, without one, its first DNS name. See gncfg TLSClientCAFile.
+func MTLSAuth() Authenticator {
+	return AuthenticatorFunc(func(r *http.Request) (*Caller, error) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			return nil, nil
+		}
+		leaf := r.TLS.VerifiedChains[0][0]
+		id := leaf.Subject.CommonName
+		if id == "" && len(leaf.DNSNames) > 0 {
+			id = leaf.DNSNames[0]
+		}
+		if id == "" {
+			return nil, errors.New("client certificate names no identity")
+		}
+		return &Caller{ID: id, Via: "mtls"}, nil
+	})
+}
@@ -0,0 +1,97 @@
+package register
+
+import (
+	"bytes"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/goccy/go-json"
+	"github.com/vmihailenco/msgpack/v5"
+	"mime"
+	"strings"
+	"sync"
+)
+
+// Codec serializes call envelopes and the arguments/results inside them.
+// JSON nests values as raw JSON; binary codecs nest each value as a byte string
+// encoded with the same codec.
+type Codec interface {
+	Name() string
+	ContentType() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var (
+	JSONCodec    Codec = jsonCodec{}
+	MsgpackCodec Codec = msgpackCodec{}
+	CBORCodec    Codec = cborCodec{}
+
+	codecMu sync.RWMutex
+	codecs  = map[string]Codec{}
+)
+
+func init() {
+	RegisterCodec(JSONCodec)
+	RegisterCodec(MsgpackCodec)
+	RegisterCodec(CBORCodec)
+}
+
+// RegisterCodec makes c selectable by its content type (e.g. a protobuf codec).
+func RegisterCodec(c Codec) {
+	codecMu.Lock()
+	defer codecMu.Unlock()
+	codecs[strings.ToLower(c.ContentType())] = c
+}
+
+// CodecFor returns the codec registered for a Content-Type or Accept value.
+// Parameters are ignored and Accept lists are tried in order.
+func CodecFor(contentType string) (Codec, bool) {
+	codecMu.RLock()
+	defer codecMu.RUnlock()
+	for _, part := range strings.Split(contentType, ",") {
+		mt, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if c, ok := codecs[mt]; ok {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string                               { return "json" }
+func (jsonCodec) ContentType() string                        { return "application/json" }
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// msgpackCodec honours json tags so payload types need no extra annotations.
+type msgpackCodec struct{}
+
+func (msgpackCodec) Name() string        { return "msgpack" }
+func (msgpackCodec) ContentType() string { return "application/msgpack" }
+
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
+
+// cborCodec falls back to json tags when no cbor tag is present.
+type cborCodec struct{}
+
+func (cborCodec) Name() string                               { return "cbor" }
+func (cborCodec) ContentType() string                        { return "application/cbor" }
+func (cborCodec) Marshal(v interface{}) ([]byte, error)      { return cbor.Marshal(v) }
+func (cborCodec) Unmarshal(data []byte, v interface{}) error { return cbor.Unmarshal(data, v) }
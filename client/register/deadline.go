@@ -0,0 +1,56 @@
+package register
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Deadline headers carried between nodes. The relative form is preferred as it does not
+// depend on synchronized clocks; the absolute one is accepted for callers that only know a wall time.
+const (
+	HeaderTimeout  = "X-Gn-Timeout"  // remaining budget in milliseconds
+	HeaderDeadline = "X-Gn-Deadline" // absolute deadline in unix milliseconds
+)
+
+// maxHeaderTimeout caps the budget read from HeaderTimeout; larger values mean "far in the
+// future" and would otherwise overflow time.Duration.
+const maxHeaderTimeout = 24 * time.Hour
+
+// SetDeadlineHeader passes the remaining budget of ctx on to the next node, if ctx has a deadline.
+func SetDeadlineHeader(h http.Header, ctx context.Context) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return
+	}
+	ms := time.Until(deadline).Milliseconds()
+	if ms < 0 {
+		ms = 0
+	}
+	h.Set(HeaderTimeout, strconv.FormatInt(ms, 10))
+}
+
+// DeadlineFromHeader reads the caller's deadline. ok is false when the caller sent none;
+// when both headers are present the earlier deadline wins.
+func DeadlineFromHeader(h http.Header) (deadline time.Time, ok bool, err error) {
+	if v := h.Get(HeaderTimeout); v != "" {
+		ms, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || ms < 0 {
+			return time.Time{}, false, fmt.Errorf("invalid %s header %q", HeaderTimeout, v)
+		}
+		ms = min(ms, maxHeaderTimeout.Milliseconds())
+		deadline, ok = time.Now().Add(time.Duration(ms)*time.Millisecond), true
+	}
+	if v := h.Get(HeaderDeadline); v != "" {
+		ms, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid %s header %q", HeaderDeadline, v)
+		}
+		if d := time.UnixMilli(ms); !ok || d.Before(deadline) {
+			deadline, ok = d, true
+		}
+	}
+	return deadline, ok, nil
+}
@@ -0,0 +1,254 @@
+package register
+
+import (
+	"context"
+	"errors"
+	"github.com/jom-io/gorig-node/gncfg"
+	"github.com/jom-io/gorig/utils/logger"
+	"go.uber.org/zap"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	discoveryPollInterval = 30 * time.Second
+	discoveryWatchWait    = 30 * time.Second
+	discoveryRetryMin     = time.Second
+	discoveryRetryMax     = 30 * time.Second
+	discoveryIdleTTL      = 10 * time.Minute
+
+	discoveryMu      sync.Mutex
+	discoveryEntries = map[string]*discoveryEntry{}
+)
+
+// discoveryEntry caches the instance list of one service/env/version and keeps it fresh.
+type discoveryEntry struct {
+	service string
+	env     string
+	version string
+
+	mu        sync.RWMutex
+	instances []Instance
+	revision  string
+	fetched   bool
+
+	fetchMu  sync.Mutex // serializes the synchronous first fetch
+	lastUsed atomic.Int64
+	cancel   context.CancelFunc
+}
+
+// Resolve returns the instances serving service, filtered by env/version (empty matches any).
+// Results come from a local cache that is refreshed from the hub in the background; when the hub
+// is unavailable the last known list keeps being served.
+func Resolve(service, env, version string) ([]Instance, error) {
+	return ResolveContext(context.Background(), service, env, version)
+}
+
+// ResolveContext is Resolve bounded by ctx for the first, uncached hub query.
+func ResolveContext(ctx context.Context, service, env, version string) ([]Instance, error) {
+	if gncfg.Cfg.HubAddr == "" {
+		return nil, errors.New("HubAddr not set via UseConfig")
+	}
+
+	e := loadDiscoveryEntry(service, env, version)
+	e.lastUsed.Store(time.Now().UnixNano())
+	if ins, ok := e.snapshot(); ok {
+		return ins, nil
+	}
+
+	e.fetchMu.Lock()
+	defer e.fetchMu.Unlock()
+	if ins, ok := e.snapshot(); ok {
+		return ins, nil
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, hubTimeout())
+		defer cancel()
+	}
+	var resp discoverResponse
+	err := eachHub(ctx, gncfg.HubPolicyFailover, func(h *hubNode) error {
+		var err error
+		resp, err = sendDiscoverRequest(ctx, hubClient(), h.addr, "/discover", discoverRequest{
+			Service: service,
+			Version: version,
+			Env:     env,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	e.update(resp)
+	e.startWatch()
+	return e.mustSnapshot(), nil
+}
+
+// StopDiscovery stops all background refreshes and drops the cached instance lists.
+func StopDiscovery() {
+	discoveryMu.Lock()
+	defer discoveryMu.Unlock()
+	for key, e := range discoveryEntries {
+		if e.cancel != nil {
+			e.cancel()
+		}
+		delete(discoveryEntries, key)
+	}
+}
+
+func discoveryKey(service, env, version string) string {
+	return service + "|" + env + "|" + version
+}
+
+func loadDiscoveryEntry(service, env, version string) *discoveryEntry {
+	key := discoveryKey(service, env, version)
+	discoveryMu.Lock()
+	defer discoveryMu.Unlock()
+	if e, ok := discoveryEntries[key]; ok {
+		return e
+	}
+	e := &discoveryEntry{service: service, env: env, version: version}
+	discoveryEntries[key] = e
+	return e
+}
+
+func (e *discoveryEntry) snapshot() ([]Instance, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if !e.fetched {
+		return nil, false
+	}
+	return append([]Instance(nil), e.instances...), true
+}
+
+func (e *discoveryEntry) mustSnapshot() []Instance {
+	ins, _ := e.snapshot()
+	return ins
+}
+
+func (e *discoveryEntry) update(resp discoverResponse) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.instances = resp.Instances
+	e.revision = resp.Revision
+	e.fetched = true
+}
+
+func (e *discoveryEntry) currentRevision() string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.revision
+}
+
+func (e *discoveryEntry) startWatch() {
+	discoveryMu.Lock()
+	defer discoveryMu.Unlock()
+	if e.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel = cancel
+	go e.watch(ctx)
+}
+
+// watch refreshes the entry until it is stopped or stays unused for discoveryIdleTTL.
+// It long-polls the hub while the hub supports it and falls back to interval polling otherwise.
+// Each round goes to the first reachable hub.
+func (e *discoveryEntry) watch(ctx context.Context) {
+	longPoll := true
+	retry := discoveryRetryMin
+
+	for {
+		if time.Since(time.Unix(0, e.lastUsed.Load())) > discoveryIdleTTL {
+			e.evict()
+			return
+		}
+
+		var (
+			resp discoverResponse
+			err  error
+		)
+		if longPoll {
+			revision := e.currentRevision()
+			started := time.Now()
+			wctx, cancel := context.WithTimeout(ctx, discoveryWatchWait+hubTimeout())
+			err = eachHub(wctx, gncfg.HubPolicyFailover, func(h *hubNode) error {
+				var err error
+				resp, err = sendDiscoverWatch(wctx, h.addr, e.service, e.env, e.version, revision, discoveryWatchWait)
+				return err
+			})
+			cancel()
+			var se *hubStatusError
+			if errors.As(err, &se) && (se.Status == http.StatusNotFound || se.Status == http.StatusMethodNotAllowed) {
+				longPoll = false
+				continue
+			}
+			if err == nil && resp.Revision == "" {
+				// hub answers without revisions, so it cannot block until a change
+				longPoll = false
+			} else if err == nil && resp.Revision == revision && time.Since(started) < discoveryRetryMin {
+				// unchanged answer that did not block; avoid spinning on the hub
+				if !sleepCtx(ctx, discoveryRetryMin) {
+					return
+				}
+			}
+		} else {
+			if !sleepCtx(ctx, discoveryPollInterval) {
+				return
+			}
+			rctx, cancel := context.WithTimeout(ctx, hubTimeout())
+			err = eachHub(rctx, gncfg.HubPolicyFailover, func(h *hubNode) error {
+				var err error
+				resp, err = sendDiscoverRequest(rctx, hubClient(), h.addr, "/discover", discoverRequest{
+					Service: e.service,
+					Version: e.version,
+					Env:     e.env,
+				})
+				return err
+			})
+			cancel()
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			logger.Warn(context.Background(), "discovery refresh failed, keep last known instances",
+				zap.String("service", e.service), zap.String("env", e.env), zap.String("version", e.version), zap.Error(err))
+			if !sleepCtx(ctx, retry) {
+				return
+			}
+			retry = min(retry*2, discoveryRetryMax)
+			continue
+		}
+		retry = discoveryRetryMin
+		e.update(resp)
+	}
+}
+
+func (e *discoveryEntry) evict() {
+	discoveryMu.Lock()
+	defer discoveryMu.Unlock()
+	key := discoveryKey(e.service, e.env, e.version)
+	if discoveryEntries[key] == e {
+		delete(discoveryEntries, key)
+	}
+	if e.cancel != nil {
+		e.cancel()
+	}
+}
+
+// sleepCtx waits for d and reports false if ctx ended first.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
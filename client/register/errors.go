@@ -0,0 +1,97 @@
+package register
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Codes used by the node itself; handlers are free to use their own.
+const (
+	CodeInternal    = "internal"
+	CodeBadRequest  = "bad_request"
+	CodeNotFound    = "not_found"
+	CodeUnavailable = "unavailable"
+	CodeDeadline    = "deadline_exceeded"
+
+	CodeUnauthenticated  = "unauthenticated"
+	CodePermissionDenied = "permission_denied"
+	CodeOverloaded       = "overloaded"
+)
+
+// Error is a structured remote error. A handler returns it (directly or wrapped) to give
+// callers a code, details and a retry hint; UnpackResponse rebuilds it on the caller side
+// so errors.As works across nodes.
+type Error struct {
+	Code      string                 `json:"code"`
+	Message   string                 `json:"message"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	Retryable bool                   `json:"retryable,omitempty"`
+	TraceID   string                 `json:"trace_id,omitempty"`
+}
+
+// NewError("order_not_found", "order 42 does not exist")
+func NewError(code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Errorf is NewError with a formatted message.
+func Errorf(code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// WithDetail returns a copy of e carrying an extra detail entry.
+func (e *Error) WithDetail(key string, value interface{}) *Error {
+	cp := e.clone()
+	if cp.Details == nil {
+		cp.Details = map[string]interface{}{}
+	}
+	cp.Details[key] = value
+	return cp
+}
+
+// WithRetryable returns a copy of e marked as safe (or not) to retry.
+func (e *Error) WithRetryable(retryable bool) *Error {
+	cp := e.clone()
+	cp.Retryable = retryable
+	return cp
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("[%s] %s", e.Code, e.Message)
+}
+
+// Is matches another *Error with the same code, so sentinel errors work with errors.Is.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code != "" && t.Code == e.Code
+}
+
+func (e *Error) clone() *Error {
+	cp := *e
+	if e.Details != nil {
+		cp.Details = make(map[string]interface{}, len(e.Details))
+		for k, v := range e.Details {
+			cp.Details[k] = v
+		}
+	}
+	return &cp
+}
+
+// errorInfo extracts the structured part of err, if any, as a private copy. Code, details and
+// the retry hint come from the wrapped *Error; the message keeps the context added around it,
+// e.g. "load order 42: item is out of stock", without repeating the code.
+func errorInfo(err error) *Error {
+	var e *Error
+	if !errors.As(err, &e) || e == nil {
+		return nil
+	}
+	cp := e.clone()
+	if full := err.Error(); full != e.Error() {
+		cp.Message = strings.Replace(full, e.Error(), e.Message, 1)
+	}
+	return cp
+}
@@ -0,0 +1,155 @@
+package register
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jom-io/gorig-node/gncfg"
+	"sync"
+	"time"
+)
+
+// healthCheckTimeout bounds one round of a service's health checks.
+var healthCheckTimeout = 2 * time.Second
+
+// ErrHealthPending is reported by Health until the first round of checks has finished.
+var ErrHealthPending = errors.New("health not checked yet")
+
+var (
+	healthMu     sync.Mutex
+	healthCancel context.CancelFunc
+)
+
+// healthState caches the latest outcome of a service's health checks, see startHealthLoop.
+type healthState struct {
+	mu      sync.Mutex
+	checked bool
+	running bool
+	err     error
+}
+
+// begin claims the next round; it fails while the previous one is still running.
+func (h *healthState) begin() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.running {
+		return false
+	}
+	h.running = true
+	return true
+}
+
+func (h *healthState) finish(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.running = false
+	h.checked = true
+	h.err = err
+}
+
+// Health values reported in heartbeats for services with health checks.
+const (
+	HealthOK      = "ok"
+	HealthFailing = "failing"
+)
+
+// HealthCheck adds a check that must pass for the node to report the service ready,
+// e.g. pinging its database. Checks run in the background every gn.health.interval;
+// /readyz and heartbeats report the latest result.
+func (s *ServerCreator) HealthCheck(check func(ctx context.Context) error) *ServerCreator {
+	if s.srv != nil && check != nil {
+		s.srv.healthChecks = append(s.srv.healthChecks, check)
+	}
+	return s
+}
+
+// CheckHealth runs the service's health checks and joins their failures.
+func (s *ServerRegister) CheckHealth(ctx context.Context) error {
+	if len(s.healthChecks) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	var errs []error
+	for i, check := range s.healthChecks {
+		if err := check(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("check %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// HasHealthChecks reports whether the service registered any HealthCheck.
+func (s *ServerRegister) HasHealthChecks() bool {
+	return len(s.healthChecks) > 0
+}
+
+// Health returns the latest cached result of the service's health checks: nil for a
+// service without checks, ErrHealthPending before the first round has finished.
+func (s *ServerRegister) Health() error {
+	if len(s.healthChecks) == 0 {
+		return nil
+	}
+	s.health.mu.Lock()
+	defer s.health.mu.Unlock()
+	if !s.health.checked {
+		return ErrHealthPending
+	}
+	return s.health.err
+}
+
+// startHealthLoop runs the health checks of every service right away and then every
+// gn.health.interval. Each service is checked on its own goroutine, so a slow check holds up
+// neither the other services nor heartbeats; a round still running is not started again.
+func startHealthLoop() {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	if healthCancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	healthCancel = cancel
+
+	go func() {
+		checkAllHealth(ctx)
+		for sleepCtx(ctx, gncfg.Current().HealthInterval) {
+			checkAllHealth(ctx)
+		}
+	}()
+}
+
+func stopHealthLoop() {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	if healthCancel != nil {
+		healthCancel()
+		healthCancel = nil
+	}
+}
+
+func checkAllHealth(ctx context.Context) {
+	registeredServers.Range(func(_, value interface{}) bool {
+		srv := value.(*ServerRegister)
+		if !srv.created || len(srv.healthChecks) == 0 || !srv.health.begin() {
+			return true
+		}
+		go func() {
+			srv.health.finish(srv.CheckHealth(ctx))
+		}()
+		return true
+	})
+}
+
+// Unregistered lists created services the hub does not currently have, sorted by name.
+// Services taken out with Deregister are left out on purpose. A node is ready to take calls
+// only when it is empty.
+func Unregistered() []ServerName {
+	var names []ServerName
+	for _, st := range Statuses() {
+		if st.Created && !st.Registered && !st.Deregistered {
+			names = append(names, st.Service)
+		}
+	}
+	return names
+}
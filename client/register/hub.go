@@ -3,11 +3,18 @@ package register
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/goccy/go-json"
+	"github.com/jom-io/gorig-node/gncfg"
+	"github.com/jom-io/gorig-node/gntrace"
+	"github.com/jom-io/gorig-node/internal/metrics"
+	"github.com/jom-io/gorig-node/internal/stats"
 	"github.com/jom-io/gorig/utils/logger"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"io"
+	"math/rand/v2"
 	"net/http"
 	"strings"
 	"sync"
@@ -19,6 +26,7 @@ type registerRequest struct {
 	Version string    `json:"version,omitempty"`
 	Env     string    `json:"env,omitempty"`
 	Host    string    `json:"host"`
+	Scheme  string    `json:"scheme"` // "http" or "https", how callers must reach Host
 	Apis    []ApiInfo `json:"apis"`
 	// legacy for hub compatibility (kept for a while)
 	ServiceLegacy string `json:"ServiceName,omitempty"`
@@ -27,27 +35,104 @@ type registerRequest struct {
 	HostLegacy    string `json:"Host,omitempty"`
 }
 
-type heartbeatRequest struct {
+type deregisterRequest struct {
 	Service string `json:"service"`
 	Host    string `json:"host"`
 }
 
+// heartbeatRequest fields beyond Service and Host are additive; hubs that reject them
+// get the legacy shape, see sendHeartbeatBatch.
+type heartbeatRequest struct {
+	Service     string         `json:"service"`
+	Host        string         `json:"host"`
+	Health      string         `json:"health,omitempty"` // only set for services with health checks that have run
+	HealthError string         `json:"health_error,omitempty"`
+	Status      string         `json:"status,omitempty"` // statusServing, statusDraining, statusDegraded or statusShedding
+	Load        *heartbeatLoad `json:"load,omitempty"`
+}
+
+// heartbeatLoad is the service's load over the last minute.
+type heartbeatLoad struct {
+	InFlight  int64   `json:"in_flight"`
+	Requests  uint64  `json:"requests"`
+	ErrorRate float64 `json:"error_rate"`
+	P99Ms     float64 `json:"p99_ms"`
+}
+
 type heartbeatBatchRequest struct {
 	Services []heartbeatRequest `json:"services"`
+	Node     *nodeLoad          `json:"node,omitempty"`
+}
+
+// nodeLoad is the load of the whole process, shared by all its services.
+type nodeLoad struct {
+	CPUPercent       float64 `json:"cpu_percent"`
+	MemBytes         uint64  `json:"mem_bytes"`
+	ConcurrencyLimit int64   `json:"concurrency_limit,omitempty"` // learned by the adaptive limiter, when enabled
+}
+
+// Node status reported per service in heartbeats.
+const (
+	statusServing  = "serving"
+	statusDraining = "draining" // the node refuses new calls before shutting down
+	statusDegraded = "degraded" // a health check of the service fails
+	statusShedding = "shedding" // the node turned calls away over its adaptive concurrency limit
+)
+
+// legacy strips everything a hub predating load reporting may reject.
+func (b heartbeatBatchRequest) legacy() heartbeatBatchRequest {
+	out := heartbeatBatchRequest{Services: make([]heartbeatRequest, 0, len(b.Services))}
+	for _, hb := range b.Services {
+		out.Services = append(out.Services, heartbeatRequest{Service: hb.Service, Host: hb.Host})
+	}
+	return out
+}
+
+// heartbeatResponse is optional; hubs that answer with an empty or foreign body are treated as "all good".
+type heartbeatResponse struct {
+	Epoch   string   `json:"epoch,omitempty"`   // changes whenever the hub restarts and loses its state
+	Unknown []string `json:"unknown,omitempty"` // services the hub holds no registration for
+}
+
+type discoverRequest struct {
+	Service string `json:"service"`
+	Version string `json:"version,omitempty"`
+	Env     string `json:"env,omitempty"`
+	// watch only: return once the hub revision differs from Revision or WaitMs elapses
+	Revision string `json:"revision,omitempty"`
+	WaitMs   int64  `json:"wait_ms,omitempty"`
+}
+
+type discoverResponse struct {
+	Service   string     `json:"service"`
+	Revision  string     `json:"revision,omitempty"`
+	Instances []Instance `json:"instances"`
 }
 
+// Instance is a single node serving a service, as reported by the hub.
+type Instance struct {
+	Service string `json:"service"`
+	Host    string `json:"host"`
+	Scheme  string `json:"scheme,omitempty"` // "https" for TLS nodes; empty means http
+	Version string `json:"version,omitempty"`
+	Env     string `json:"env,omitempty"`
+	Weight  int    `json:"weight,omitempty"` // relative share for weighted balancing; <= 0 counts as 1
+}
+
+// Intervals, timeouts and retry policy come from gncfg.Current(), see gncfg.GlobalConfig.
 var (
-	requestTimeout    = 5 * time.Second
-	heartbeatInterval = time.Minute
-	httpClient        = &http.Client{Timeout: requestTimeout}
-	heartbeatMu       sync.Mutex
-	heartbeatCancel   context.CancelFunc
-	heartbeatRunning  bool
-	enableHBLog       bool
+	heartbeatMu      sync.Mutex
+	heartbeatCancel  context.CancelFunc
+	heartbeatRunning bool
+	enableHBLog      bool
+
+	retryMu     sync.Mutex
+	retryCtx    context.Context
+	retryCancel context.CancelFunc
 )
 
 func sendRegisterWithTimeout(hubAddr string, srv *ServerRegister) error {
-	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), hubTimeout())
 	defer cancel()
 	return sendRegister(ctx, hubAddr, srv)
 }
@@ -59,6 +144,7 @@ func sendRegister(ctx context.Context, hubAddr string, srv *ServerRegister) erro
 		Version:       srv.Version,
 		Env:           srv.Environment,
 		Host:          srv.Host,
+		Scheme:        nodeScheme(),
 		Apis:          srv.Apis,
 		ServiceLegacy: srv.ServiceName,
 		VersionLegacy: srv.Version,
@@ -68,21 +154,69 @@ func sendRegister(ctx context.Context, hubAddr string, srv *ServerRegister) erro
 	return postJSON(ctx, url, payload)
 }
 
-func sendHeartbeatBatchWithTimeout(hubAddr string, batch heartbeatBatchRequest) error {
-	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+func sendDeregister(ctx context.Context, hubAddr string, srv *ServerRegister) error {
+	url := buildHubURL(hubAddr, "/deregister")
+	payload := deregisterRequest{
+		Service: srv.ServiceName,
+		Host:    srv.Host,
+	}
+	return postJSON(ctx, url, payload)
+}
+
+func sendHeartbeatBatchWithTimeout(h *hubNode, batch heartbeatBatchRequest) (heartbeatResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), hubTimeout())
 	defer cancel()
-	return sendHeartbeatBatch(ctx, hubAddr, batch)
+	return sendHeartbeatBatch(ctx, h, batch)
 }
 
-func sendHeartbeatBatch(ctx context.Context, hubAddr string, batch heartbeatBatchRequest) error {
+func sendHeartbeatBatch(ctx context.Context, h *hubNode, batch heartbeatBatchRequest) (heartbeatResponse, error) {
+	var resp heartbeatResponse
 	if len(batch.Services) == 0 {
-		return nil
+		return resp, nil
 	}
-	url := buildHubURL(hubAddr, "/heartbeat")
-	return postJSON(ctx, url, batch)
+	url := buildHubURL(h.addr, "/heartbeat")
+	if h.legacy.Load() {
+		batch = batch.legacy()
+	}
+	body, err := doPost(ctx, hubClient(), url, batch)
+	var se *hubStatusError
+	if err != nil && !h.legacy.Load() && errors.As(err, &se) && se.Status == http.StatusBadRequest && !isUnknownServiceStatus(se) {
+		logger.Warn(context.Background(), "hub rejected the extended heartbeat, falling back to the legacy shape", zap.String("hub", h.addr), zap.Error(err))
+		h.legacy.Store(true)
+		body, err = doPost(ctx, hubClient(), url, batch.legacy())
+	}
+	if err != nil {
+		return resp, err
+	}
+	_ = json.Unmarshal(body, &resp)
+	return resp, nil
+}
+
+// sendDiscoverWatch long-polls the hub until the instance list moves past revision or wait elapses.
+func sendDiscoverWatch(ctx context.Context, hubAddr, service, env, version, revision string, wait time.Duration) (discoverResponse, error) {
+	return sendDiscoverRequest(ctx, hubWatchClient(), hubAddr, "/discover/watch", discoverRequest{
+		Service:  service,
+		Version:  version,
+		Env:      env,
+		Revision: revision,
+		WaitMs:   wait.Milliseconds(),
+	})
 }
 
-func startHeartbeatLoop(hubAddr string) {
+func sendDiscoverRequest(ctx context.Context, client *http.Client, hubAddr, path string, payload discoverRequest) (discoverResponse, error) {
+	var resp discoverResponse
+	if err := doPostJSON(ctx, client, buildHubURL(hubAddr, path), payload, &resp); err != nil {
+		return resp, err
+	}
+	for i := range resp.Instances {
+		if resp.Instances[i].Service == "" {
+			resp.Instances[i].Service = payload.Service
+		}
+	}
+	return resp, nil
+}
+
+func startHeartbeatLoop() {
 	heartbeatMu.Lock()
 	if heartbeatRunning {
 		heartbeatMu.Unlock()
@@ -92,6 +226,7 @@ func startHeartbeatLoop(hubAddr string) {
 	heartbeatRunning = true
 	heartbeatCancel = cancel
 	heartbeatMu.Unlock()
+	cfg := gncfg.Current()
 
 	go func() {
 		defer func() {
@@ -101,30 +236,44 @@ func startHeartbeatLoop(hubAddr string) {
 			heartbeatMu.Unlock()
 		}()
 
-		sendHeartbeatsOnce(hubAddr)
-
-		ticker := time.NewTicker(heartbeatInterval)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-ticker.C:
-				sendHeartbeatsOnce(hubAddr)
-			}
+		sendHeartbeatsOnce()
+		for sleepCtx(ctx, heartbeatDelay(cfg)) {
+			sendHeartbeatsOnce()
 		}
 	}()
 }
 
+// heartbeatDelay is the configured interval plus a random share of the jitter,
+// so nodes started together do not beat in lockstep.
+func heartbeatDelay(cfg gncfg.GlobalConfig) time.Duration {
+	if cfg.HeartbeatJitter <= 0 {
+		return cfg.HeartbeatInterval
+	}
+	return cfg.HeartbeatInterval + rand.N(cfg.HeartbeatJitter)
+}
+
+func hubTimeout() time.Duration {
+	return gncfg.Current().HubTimeout
+}
+
 func Stop() {
+	stopHealthLoop()
+
 	heartbeatMu.Lock()
-	defer heartbeatMu.Unlock()
 	if heartbeatCancel != nil {
 		heartbeatCancel()
 	}
 	heartbeatRunning = false
 	heartbeatCancel = nil
+	heartbeatMu.Unlock()
+
+	retryMu.Lock()
+	if retryCancel != nil {
+		retryCancel()
+	}
+	retryCtx = nil
+	retryCancel = nil
+	retryMu.Unlock()
 }
 
 // EnableHeartbeatLog controls whether heartbeat success logs are printed (disabled by default).
@@ -132,68 +281,348 @@ func EnableHeartbeatLog(enable bool) {
 	enableHBLog = enable
 }
 
+// hubStatusError is a non-2xx answer from the hub.
+type hubStatusError struct {
+	URL    string
+	Status int
+	Body   string
+}
+
+func (e *hubStatusError) Error() string {
+	return fmt.Sprintf("request %s failed with status %d: %s", e.URL, e.Status, e.Body)
+}
+
+// nodeScheme is the scheme the inbound server of this node answers on.
+func nodeScheme() string {
+	if gncfg.Current().InboundTLS() {
+		return "https"
+	}
+	return "http"
+}
+
+// buildHubURL joins base and path; hubs without a scheme use https once hub TLS is configured.
 func buildHubURL(base, path string) string {
 	if !strings.Contains(base, "://") {
-		base = "http://" + base
+		if gncfg.Current().HubTLS() {
+			base = "https://" + base
+		} else {
+			base = "http://" + base
+		}
 	}
 	return strings.TrimRight(base, "/") + "/" + strings.TrimLeft(path, "/")
 }
 
 func postJSON(ctx context.Context, url string, payload interface{}) error {
-	body, err := json.Marshal(payload)
+	return doPostJSON(ctx, hubClient(), url, payload, nil)
+}
+
+// doPostJSON posts payload and decodes the response body into out when out is non-nil.
+func doPostJSON(ctx context.Context, client *http.Client, url string, payload interface{}, out interface{}) error {
+	body, err := doPost(ctx, client, url, payload)
 	if err != nil {
 		return err
 	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("request %s decode response failed: %w", url, err)
+	}
+	return nil
+}
+
+// doPost posts payload as JSON and returns the response body of a 2xx answer.
+func doPost(ctx context.Context, client *http.Client, url string, payload interface{}) (respBody []byte, err error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	authorizeHubRequest(req, body)
+
+	ctx, span := gntrace.StartCall(ctx, "hub", strings.TrimPrefix(req.URL.Path, "/"), trace.SpanKindClient)
+	status := 0
+	defer func() {
+		errMsg := ""
+		if err != nil {
+			errMsg = err.Error()
+		}
+		gntrace.EndCall(span, status, errMsg)
+	}()
+	req = req.WithContext(ctx)
+	gntrace.Inject(ctx, req.Header)
 
-	resp, err := httpClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
+	status = resp.StatusCode
 
 	if resp.StatusCode >= http.StatusMultipleChoices {
 		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
-		return fmt.Errorf("request %s failed with status %d: %s", url, resp.StatusCode, strings.TrimSpace(string(respBody)))
+		return nil, &hubStatusError{URL: url, Status: resp.StatusCode, Body: strings.TrimSpace(string(respBody))}
 	}
 
-	_, _ = io.Copy(io.Discard, resp.Body)
-	return nil
+	return io.ReadAll(resp.Body)
 }
 
-// sendHeartbeatsOnce sends heartbeats for all created services.
-func sendHeartbeatsOnce(hubAddr string) {
+// sendHeartbeatsOnce sends heartbeats for all registered services to the hubs of the
+// configured policy. A service counts as beating when at least one hub took the batch.
+func sendHeartbeatsOnce() {
 	var (
-		batch   heartbeatBatchRequest
-		records []string
+		batch    heartbeatBatchRequest
+		records  []string
+		services []ServerName
 	)
+	// shedding is latched until a heartbeat reports it, however short it was
+	shed, shedMark := stats.ShedSinceReport()
 	registeredServers.Range(func(_, value interface{}) bool {
 		srv := value.(*ServerRegister)
-		if !srv.created || srv.Host == "" {
+		if !srv.created || !srv.registered.Load() || srv.Host == "" {
 			return true
 		}
-		batch.Services = append(batch.Services, heartbeatRequest{
+		hb := heartbeatRequest{
 			Service: srv.ServiceName,
 			Host:    srv.Host,
-		})
+		}
+		// the cached result; checks run on their own goroutines, see startHealthLoop
+		switch err := srv.Health(); {
+		case !srv.HasHealthChecks() || errors.Is(err, ErrHealthPending):
+		case err != nil:
+			hb.Health = HealthFailing
+			hb.HealthError = err.Error()
+		default:
+			hb.Health = HealthOK
+		}
+		switch {
+		case stats.Draining():
+			hb.Status = statusDraining
+		case hb.Health == HealthFailing:
+			hb.Status = statusDegraded
+		case shed:
+			hb.Status = statusShedding
+		default:
+			hb.Status = statusServing
+		}
+		load := stats.ServiceLoad(srv.ServiceName)
+		hb.Load = &heartbeatLoad{
+			InFlight:  load.InFlight,
+			Requests:  load.Requests,
+			ErrorRate: load.ErrorRate,
+			P99Ms:     float64(load.P99) / float64(time.Millisecond),
+		}
+		batch.Services = append(batch.Services, hb)
 		records = append(records, fmt.Sprintf("%s@%s", srv.ServiceName, srv.Host))
+		services = append(services, srv.ServiceName)
 		return true
 	})
 
 	if len(batch.Services) == 0 {
 		return
 	}
+	cpu, mem := stats.Process()
+	batch.Node = &nodeLoad{CPUPercent: cpu, MemBytes: mem, ConcurrencyLimit: stats.ConcurrencyLimit()}
 
-	if err := sendHeartbeatBatchWithTimeout(hubAddr, batch); err != nil {
-		logger.Error(context.Background(), "heartbeat failed", zap.String("hub", hubAddr), zap.Strings("services", records), zap.Error(err))
-		return
+	var (
+		unknownMu sync.Mutex
+		unknown   []ServerName
+	)
+	err := eachHub(context.Background(), gncfg.Current().HubPolicy, func(h *hubNode) error {
+		names, err := beatHub(h, batch, services, records)
+		unknownMu.Lock()
+		unknown = append(unknown, names...)
+		unknownMu.Unlock()
+		return err
+	})
+	if err == nil {
+		stats.MarkShedReported(shedMark)
+	}
+	recordHeartbeats(services, err)
+	recordHeartbeats(unknown, errors.New("hub reported the service unknown"))
+	metrics.HeartbeatResult(err == nil)
+}
+
+// beatHub sends the batch to one hub and reacts to what the hub knows about the node.
+// It returns the services the hub reported unknown.
+func beatHub(h *hubNode, batch heartbeatBatchRequest, services []ServerName, records []string) ([]ServerName, error) {
+	resp, err := sendHeartbeatBatchWithTimeout(h, batch)
+	if err != nil {
+		var se *hubStatusError
+		if errors.As(err, &se) && isUnknownServiceStatus(se) {
+			logger.Warn(context.Background(), "hub does not know heartbeat services, re-registering", zap.String("hub", h.addr), zap.Strings("services", records), zap.Error(err))
+			reRegister(h, services)
+			return nil, err
+		}
+		logger.Error(context.Background(), "heartbeat failed", zap.String("hub", h.addr), zap.Strings("services", records), zap.Error(err))
+		return nil, err
+	}
+
+	epochChanged := h.swapEpoch(resp.Epoch)
+	if epochChanged {
+		// the restarted hub may be a newer version; offer it the extended heartbeat again
+		h.legacy.Store(false)
+	}
+	switch {
+	case epochChanged:
+		logger.Warn(context.Background(), "hub epoch changed, re-registering", zap.String("hub", h.addr), zap.String("epoch", resp.Epoch), zap.Strings("services", records))
+		reRegister(h, services)
+	case len(resp.Unknown) > 0:
+		logger.Warn(context.Background(), "hub reported unknown services, re-registering", zap.String("hub", h.addr), zap.Strings("services", resp.Unknown))
+		reRegister(h, resp.Unknown)
 	}
 	if enableHBLog {
-		logger.Info(context.Background(), "heartbeat succeeded", zap.String("hub", hubAddr), zap.Strings("services", records))
+		logger.Info(context.Background(), "heartbeat succeeded", zap.String("hub", h.addr), zap.Strings("services", records))
+	}
+	return resp.Unknown, nil
+}
+
+func recordHeartbeats(names []ServerName, err error) {
+	for _, name := range names {
+		if val, ok := registeredServers.Load(name); ok {
+			val.(*ServerRegister).hub.recordHeartbeat(err)
+		}
+	}
+}
+
+// isUnknownServiceStatus reports whether a failed heartbeat means the hub lost our registration.
+func isUnknownServiceStatus(se *hubStatusError) bool {
+	if se.Status == http.StatusNotFound || se.Status == http.StatusGone {
+		return true
+	}
+	body := strings.ToLower(se.Body)
+	return strings.Contains(body, "unknown service") || strings.Contains(body, "not registered")
+}
+
+// reRegister replays the registration of the named services with h; failures are retried
+// with backoff against the hubs of the configured policy.
+func reRegister(h *hubNode, names []ServerName) {
+	for _, name := range names {
+		val, ok := registeredServers.Load(name)
+		if !ok {
+			continue
+		}
+		srv := val.(*ServerRegister)
+		if !srv.created || srv.deregistered.Load() {
+			continue
+		}
+		if err := registerWith(h, srv); err != nil {
+			scheduleRegisterRetry(srv)
+		}
+	}
+}
+
+// registerOnce registers srv with the hubs of the configured policy. With HubPolicyAll one
+// accepting hub is enough; the hubs that refused are retried directly, see scheduleHubRetry.
+func registerOnce(srv *ServerRegister) error {
+	var (
+		mu     sync.Mutex
+		failed []*hubNode
+	)
+	policy := gncfg.Current().HubPolicy
+	err := eachHub(context.Background(), policy, func(h *hubNode) error {
+		err := registerWith(h, srv)
+		if err != nil {
+			mu.Lock()
+			failed = append(failed, h)
+			mu.Unlock()
+		}
+		return err
+	})
+	if err == nil && policy == gncfg.HubPolicyAll {
+		for _, h := range failed {
+			scheduleHubRetry(h, srv)
+		}
+	}
+	return err
+}
+
+// registerWith sends a single registration to h and marks the service registered on success.
+func registerWith(h *hubNode, srv *ServerRegister) error {
+	if err := sendRegisterWithTimeout(h.addr, srv); err != nil {
+		logger.Error(context.Background(), "report to registry failed", zap.String("service", srv.ServiceName), zap.String("hub", h.addr), zap.Error(err))
+		return err
+	}
+	srv.registered.Store(true)
+	srv.hub.recordRegistered()
+	h.releasePending(srv.ServiceName, true)
+	metrics.Registered(srv.ServiceName)
+	logger.Info(context.Background(), "report to registry succeeded", zap.String("service", srv.ServiceName), zap.String("hub", h.addr), zap.String("host", srv.Host))
+	return nil
+}
+
+// retryContext is canceled by Stop; background registration retries run under it.
+func retryContext() context.Context {
+	retryMu.Lock()
+	defer retryMu.Unlock()
+	if retryCtx == nil {
+		retryCtx, retryCancel = context.WithCancel(context.Background())
+	}
+	return retryCtx
+}
+
+// scheduleHubRetry keeps retrying the registration of srv with h alone, for a hub that refused
+// it while another hub accepted it. It stops like scheduleRegisterRetry; until it succeeds the
+// service is listed as pending in Hubs.
+func scheduleHubRetry(h *hubNode, srv *ServerRegister) {
+	if !h.claimPending(srv.ServiceName) {
+		return
 	}
+	ctx := retryContext()
+	cfg := gncfg.Current()
+
+	go func() {
+		delay := cfg.RetryMin
+		for attempt := 1; cfg.RetryLimit <= 0 || attempt <= cfg.RetryLimit; attempt++ {
+			if !sleepCtx(ctx, delay) {
+				h.releasePending(srv.ServiceName, false)
+				return
+			}
+			if srv.deregistered.Load() {
+				h.releasePending(srv.ServiceName, true)
+				return
+			}
+			err := registerWith(h, srv)
+			h.record(err)
+			if err == nil {
+				return
+			}
+			delay = min(delay*2, cfg.RetryMax)
+		}
+		h.releasePending(srv.ServiceName, false)
+		logger.Error(context.Background(), "report to registry gave up", zap.String("service", srv.ServiceName), zap.String("hub", h.addr), zap.Int("attempts", cfg.RetryLimit))
+	}()
+}
+
+// scheduleRegisterRetry keeps retrying the registration of srv with exponential backoff
+// until it succeeds, the retry limit is reached, the service is deregistered or Stop is called.
+func scheduleRegisterRetry(srv *ServerRegister) {
+	if !srv.retrying.CompareAndSwap(false, true) {
+		return
+	}
+
+	ctx := retryContext()
+	cfg := gncfg.Current()
+
+	go func() {
+		defer srv.retrying.Store(false)
+
+		delay := cfg.RetryMin
+		for attempt := 1; cfg.RetryLimit <= 0 || attempt <= cfg.RetryLimit; attempt++ {
+			if !sleepCtx(ctx, delay) || srv.deregistered.Load() {
+				return
+			}
+			if err := registerOnce(srv); err == nil {
+				startHeartbeatLoop()
+				return
+			}
+			delay = min(delay*2, cfg.RetryMax)
+		}
+		logger.Error(context.Background(), "report to registry gave up", zap.String("service", srv.ServiceName), zap.String("hub", cfg.HubAddr), zap.Int("attempts", cfg.RetryLimit))
+	}()
 }
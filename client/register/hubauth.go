@@ -0,0 +1,132 @@
+package register
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"github.com/jom-io/gorig-node/gncfg"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// HeaderHubTimestamp carries the unix seconds a signed hub request was made at.
+	HeaderHubTimestamp = "X-Gn-Timestamp"
+	// HeaderHubSignature carries the HubSignature of a hub request when gncfg HubSecret is set.
+	HeaderHubSignature = "X-Gn-Signature"
+)
+
+var (
+	customHubClient atomic.Pointer[http.Client]
+
+	hubTransportMu  sync.Mutex
+	hubTransportKey [3]string // cert, key and CA file the cached transport was built from
+	hubTransportRT  http.RoundTripper
+)
+
+// UseHubClient replaces the client used for hub requests, e.g. to go through a proxy.
+// Requests stay bounded by their contexts and are still signed from gncfg; nil restores
+// the default client built from the gn hub TLS settings.
+func UseHubClient(c *http.Client) {
+	customHubClient.Store(c)
+}
+
+// HubSignature returns the hex HMAC-SHA256, keyed with secret, over timestamp, method, path
+// and body joined by newlines. Hubs recompute it to authenticate node requests.
+func HubSignature(secret, timestamp, method, path string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + method + "\n" + path + "\n"))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// authorizeHubRequest adds the configured bearer token and HMAC signature to req.
+func authorizeHubRequest(req *http.Request, body []byte) {
+	cfg := gncfg.Current()
+	if cfg.HubToken != "" {
+		req.Header.Set("Authorization", "Bearer "+cfg.HubToken)
+	}
+	if cfg.HubSecret != "" {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(HeaderHubTimestamp, ts)
+		req.Header.Set(HeaderHubSignature, HubSignature(cfg.HubSecret, ts, req.Method, req.URL.Path, body))
+	}
+}
+
+// hubClient returns the client for hub requests, bounded by the configured hub timeout.
+// With broken hub TLS settings every request fails with the load error rather than going
+// out without the configured certificate or CA; Start reports the same error up front.
+func hubClient() *http.Client {
+	if c := customHubClient.Load(); c != nil {
+		return c
+	}
+	rt, err := hubTransport(gncfg.Current())
+	if err != nil {
+		rt = failingTransport{err: err}
+	}
+	return &http.Client{Transport: rt, Timeout: hubTimeout()}
+}
+
+// failingTransport refuses every request with err.
+type failingTransport struct {
+	err error
+}
+
+func (t failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, t.err
+}
+
+// hubWatchClient is hubClient without a timeout; long-poll requests are bounded by their context.
+func hubWatchClient() *http.Client {
+	c := *hubClient()
+	c.Timeout = 0
+	return &c
+}
+
+// hubTransport returns the transport carrying the hub TLS settings of cfg. It is built once
+// per combination of files, so certificates rotated in place need a restart.
+func hubTransport(cfg gncfg.GlobalConfig) (http.RoundTripper, error) {
+	if !cfg.HubTLS() {
+		return http.DefaultTransport, nil
+	}
+	key := [3]string{cfg.HubCertFile, cfg.HubKeyFile, cfg.HubCAFile}
+
+	hubTransportMu.Lock()
+	defer hubTransportMu.Unlock()
+	if hubTransportRT != nil && hubTransportKey == key {
+		return hubTransportRT, nil
+	}
+
+	tlsCfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.HubCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.HubCertFile, cfg.HubKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load hub client certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	if cfg.HubCAFile != "" {
+		pem, err := os.ReadFile(cfg.HubCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("load hub CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("load hub CA: no certificate found in %s", cfg.HubCAFile)
+		}
+		tlsCfg.RootCAs = pool
+	}
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.TLSClientConfig = tlsCfg
+
+	hubTransportKey = key
+	hubTransportRT = tr
+	return tr, nil
+}
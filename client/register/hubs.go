@@ -0,0 +1,314 @@
+package register
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jom-io/gorig-node/gncfg"
+	"github.com/jom-io/gorig/utils/logger"
+	"go.uber.org/zap"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// hubResolveTTL bounds how long the expansion of dns:// and srv:// hub entries is reused.
+const hubResolveTTL = 30 * time.Second
+
+// HubStatus is the health of one hub as seen by this node.
+type HubStatus struct {
+	Addr            string    `json:"addr"`
+	Healthy         bool      `json:"healthy"`
+	Failures        int       `json:"failures"` // consecutive requests that did not reach a working hub
+	LastOK          time.Time `json:"last_ok,omitempty"`
+	LastError       string    `json:"last_error,omitempty"`
+	LastErrorAt     time.Time `json:"last_error_at,omitempty"`
+	Epoch           string    `json:"epoch,omitempty"`
+	LegacyHeartbeat bool      `json:"legacy_heartbeat"`
+	// Pending lists services this hub refused while another hub accepted them under
+	// HubPolicyAll; their registration is retried with this hub directly.
+	Pending []ServerName `json:"pending,omitempty"`
+}
+
+// hubNode is one hub address and what the node learned about it.
+type hubNode struct {
+	addr   string
+	legacy atomic.Bool // the hub rejected the extended heartbeat; send service and host only
+
+	mu        sync.Mutex
+	epoch     string // only advanced by the heartbeat loop
+	failures  int
+	lastOK    time.Time
+	lastErr   string
+	lastErrAt time.Time
+	pending   map[ServerName]bool // services to register with this hub; true while a retry runs
+}
+
+var (
+	hubNodes sync.Map // addr -> *hubNode
+
+	hubResolveMu sync.Mutex
+	hubResolved  struct {
+		spec  string
+		at    time.Time
+		addrs []string
+	}
+)
+
+func hubNodeFor(addr string) *hubNode {
+	if v, ok := hubNodes.Load(addr); ok {
+		return v.(*hubNode)
+	}
+	v, _ := hubNodes.LoadOrStore(addr, &hubNode{addr: addr})
+	return v.(*hubNode)
+}
+
+// record updates the health of the hub after a request. A hub that answered, even with a
+// client error, is up; transport errors and 5xx answers count as failures. Requests the
+// caller canceled say nothing about the hub.
+func (h *hubNode) record(err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err == nil || hubAnswered(err) {
+		h.failures = 0
+		h.lastOK = time.Now()
+		return
+	}
+	h.failures++
+	h.lastErr = err.Error()
+	h.lastErrAt = time.Now()
+}
+
+// down reports whether the last request to the hub failed less than hold ago.
+func (h *hubNode) down(hold time.Duration) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.failures > 0 && time.Since(h.lastErrAt) < hold
+}
+
+// swapEpoch stores epoch and reports whether it replaced a different, known one.
+func (h *hubNode) swapEpoch(epoch string) bool {
+	if epoch == "" {
+		return false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	changed := h.epoch != "" && h.epoch != epoch
+	h.epoch = epoch
+	return changed
+}
+
+// claimPending marks name pending and reports whether the caller should start its retry.
+func (h *hubNode) claimPending(name ServerName) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.pending[name] {
+		return false
+	}
+	if h.pending == nil {
+		h.pending = map[ServerName]bool{}
+	}
+	h.pending[name] = true
+	return true
+}
+
+// releasePending records that the retry for name ended; done clears it, otherwise it stays
+// pending until a later registration with the hub succeeds.
+func (h *hubNode) releasePending(name ServerName, done bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.pending[name]; !ok {
+		return
+	}
+	if done {
+		delete(h.pending, name)
+	} else {
+		h.pending[name] = false
+	}
+}
+
+func (h *hubNode) status() HubStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var pending []ServerName
+	for name := range h.pending {
+		pending = append(pending, name)
+	}
+	sort.Strings(pending)
+	return HubStatus{
+		Addr:            h.addr,
+		Healthy:         h.failures == 0,
+		Failures:        h.failures,
+		LastOK:          h.lastOK,
+		LastError:       h.lastErr,
+		LastErrorAt:     h.lastErrAt,
+		Epoch:           h.epoch,
+		LegacyHeartbeat: h.legacy.Load(),
+		Pending:         pending,
+	}
+}
+
+// hubAnswered reports whether err is a hub decision rather than a sign the hub is unusable.
+func hubAnswered(err error) bool {
+	var se *hubStatusError
+	return errors.As(err, &se) && se.Status < http.StatusInternalServerError
+}
+
+// Hubs returns the health of every hub currently configured, in HubAddr order.
+func Hubs() []HubStatus {
+	addrs, err := resolveHubs(context.Background(), gncfg.Current())
+	if err != nil {
+		return nil
+	}
+	result := make([]HubStatus, 0, len(addrs))
+	for _, addr := range addrs {
+		result = append(result, hubNodeFor(addr).status())
+	}
+	return result
+}
+
+// resolveHubs expands the HubAddr entries of cfg into hub addresses, keeping config order
+// and dropping duplicates. Entries that fail to resolve are skipped while others remain.
+// Lookups run outside hubResolveMu so a slow resolver does not stall other hub traffic
+// that can use the cached expansion.
+func resolveHubs(ctx context.Context, cfg gncfg.GlobalConfig) ([]string, error) {
+	hubResolveMu.Lock()
+	if hubResolved.spec == cfg.HubAddr && time.Since(hubResolved.at) < hubResolveTTL {
+		addrs := hubResolved.addrs
+		hubResolveMu.Unlock()
+		return addrs, nil
+	}
+	hubResolveMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.HubTimeout)
+	defer cancel()
+
+	var (
+		addrs []string
+		errs  []error
+		seen  = map[string]bool{}
+	)
+	for _, entry := range cfg.Hubs() {
+		expanded, err := expandHub(ctx, entry)
+		if err != nil {
+			logger.Warn(context.Background(), "resolve hub failed", zap.String("hub", entry), zap.Error(err))
+			errs = append(errs, err)
+			continue
+		}
+		for _, addr := range expanded {
+			if !seen[addr] {
+				seen[addr] = true
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+	if len(addrs) == 0 {
+		if len(errs) == 0 {
+			return nil, errors.New("HubAddr not set via UseConfig")
+		}
+		return nil, errors.Join(errs...)
+	}
+
+	hubResolveMu.Lock()
+	defer hubResolveMu.Unlock()
+	hubResolved.spec = cfg.HubAddr
+	hubResolved.at = time.Now()
+	hubResolved.addrs = addrs
+	return addrs, nil
+}
+
+// expandHub turns one HubAddr entry into hub addresses.
+func expandHub(ctx context.Context, entry string) ([]string, error) {
+	switch {
+	case strings.HasPrefix(entry, "srv://"):
+		_, records, err := net.DefaultResolver.LookupSRV(ctx, "", "", strings.TrimPrefix(entry, "srv://"))
+		if err != nil {
+			return nil, err
+		}
+		// records come sorted by priority and shuffled by weight within a priority
+		addrs := make([]string, 0, len(records))
+		for _, r := range records {
+			addrs = append(addrs, net.JoinHostPort(strings.TrimSuffix(r.Target, "."), fmt.Sprint(r.Port)))
+		}
+		return addrs, nil
+	case strings.HasPrefix(entry, "dns://"):
+		host, port, err := net.SplitHostPort(strings.TrimPrefix(entry, "dns://"))
+		if err != nil {
+			return nil, err
+		}
+		ips, err := net.DefaultResolver.LookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(ips)
+		addrs := make([]string, 0, len(ips))
+		for _, ip := range ips {
+			addrs = append(addrs, net.JoinHostPort(ip, port))
+		}
+		return addrs, nil
+	default:
+		return []string{entry}, nil
+	}
+}
+
+// eachHub runs fn against the hubs of policy. With gncfg.HubPolicyAll every hub is tried in
+// parallel and nil is returned once any accepted. With gncfg.HubPolicyFailover hubs are tried
+// one at a time, recently failed ones last, until one answers. ctx only bounds DNS resolution.
+func eachHub(ctx context.Context, policy string, fn func(h *hubNode) error) error {
+	cfg := gncfg.Current()
+	addrs, err := resolveHubs(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	nodes := make([]*hubNode, len(addrs))
+	for i, addr := range addrs {
+		nodes[i] = hubNodeFor(addr)
+	}
+
+	if policy == gncfg.HubPolicyAll {
+		errs := make([]error, len(nodes))
+		var wg sync.WaitGroup
+		for i, h := range nodes {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = fn(h)
+				h.record(errs[i])
+			}()
+		}
+		wg.Wait()
+		for _, err := range errs {
+			if err == nil {
+				return nil
+			}
+		}
+		return errors.Join(errs...)
+	}
+
+	// a failed hub is moved back for one heartbeat interval, then offered its place again
+	var up, down []*hubNode
+	for _, h := range nodes {
+		if h.down(cfg.HeartbeatInterval) {
+			down = append(down, h)
+		} else {
+			up = append(up, h)
+		}
+	}
+	var errs []error
+	for _, h := range append(up, down...) {
+		err := fn(h)
+		h.record(err)
+		if err == nil || hubAnswered(err) {
+			return err
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
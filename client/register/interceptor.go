@@ -0,0 +1,183 @@
+package register
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Invocation describes one call as interceptors see it. Args are the decoded arguments
+// without ctx; an interceptor may replace them before calling next.
+type Invocation struct {
+	Service string
+	Method  string
+	Args    []interface{}
+	Meta    MethodMeta
+}
+
+// Invoker runs the rest of the chain and returns the method results without its error.
+type Invoker func(ctx context.Context, inv *Invocation) ([]interface{}, error)
+
+// Interceptor wraps a call; return without calling next to short-circuit it, e.g.
+//
+//	func audit(ctx context.Context, inv *register.Invocation, next register.Invoker) ([]interface{}, error) {
+//		res, err := next(ctx, inv)
+//		log.Printf("%s.%s err=%v", inv.Service, inv.Method, err)
+//		return res, err
+//	}
+type Interceptor func(ctx context.Context, inv *Invocation, next Invoker) ([]interface{}, error)
+
+var (
+	interceptorMu      sync.RWMutex
+	globalInterceptors []Interceptor
+)
+
+// UseInterceptor adds interceptors that run around every service's calls, before the service's own.
+func UseInterceptor(interceptors ...Interceptor) {
+	interceptorMu.Lock()
+	defer interceptorMu.Unlock()
+	globalInterceptors = append(globalInterceptors, interceptors...)
+}
+
+// Use adds interceptors that run around this service's calls, in the order given.
+func (s *ServerCreator) Use(interceptors ...Interceptor) *ServerCreator {
+	if s.srv != nil {
+		interceptorMu.Lock()
+		s.srv.interceptors = append(s.srv.interceptors, interceptors...)
+		interceptorMu.Unlock()
+	}
+	return s
+}
+
+// Invoke calls method with args as decoded by UnpackRequest (ctx value first when the method
+// takes one), running the interceptor chain around it. Every transport goes through here.
+// err is set only when an interceptor fails a method that has no error return to carry it.
+func (s *ServerRegister) Invoke(ctx context.Context, method string, args []reflect.Value) ([]reflect.Value, error) {
+	fn, ok := s.FnMap[method]
+	if !ok {
+		return nil, fmt.Errorf("method %s not found on %s", method, s.ServiceName)
+	}
+	meta := s.MethodMeta[method]
+
+	interceptorMu.RLock()
+	chain := make([]Interceptor, 0, len(globalInterceptors)+len(s.interceptors))
+	chain = append(chain, globalInterceptors...)
+	chain = append(chain, s.interceptors...)
+	interceptorMu.RUnlock()
+
+	if len(chain) == 0 {
+		return fn.Call(args), nil
+	}
+
+	offset := 0
+	if meta.HasCtx {
+		offset = 1
+	}
+	inv := &Invocation{Service: s.ServiceName, Method: method, Meta: meta}
+	for _, a := range args[offset:] {
+		inv.Args = append(inv.Args, a.Interface())
+	}
+
+	final := func(ctx context.Context, inv *Invocation) ([]interface{}, error) {
+		if len(inv.Args) != len(meta.InTypes)-offset {
+			return nil, fmt.Errorf("%s.%s expects %d args, got %d", s.ServiceName, method, len(meta.InTypes)-offset, len(inv.Args))
+		}
+		in := make([]reflect.Value, 0, len(meta.InTypes))
+		if meta.HasCtx {
+			// hand an interceptor's derived ctx to the handler when its type allows
+			cv := args[0]
+			if ctx != nil && reflect.TypeOf(ctx).AssignableTo(meta.CtxType) {
+				cv = reflect.ValueOf(ctx)
+			}
+			in = append(in, cv)
+		}
+		for i, a := range inv.Args {
+			v, err := valueAs(a, meta.InTypes[i+offset])
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s arg %d: %w", s.ServiceName, method, i, err)
+			}
+			in = append(in, v)
+		}
+		return splitResults(meta, fn.Call(in))
+	}
+
+	next := Invoker(final)
+	for i := len(chain) - 1; i >= 0; i-- {
+		ic, inner := chain[i], next
+		next = func(ctx context.Context, inv *Invocation) ([]interface{}, error) {
+			return ic(ctx, inv, inner)
+		}
+	}
+
+	results, err := next(ctx, inv)
+	return joinResults(meta, results, err)
+}
+
+// splitResults separates the method's trailing error from its other results.
+func splitResults(meta MethodMeta, outs []reflect.Value) ([]interface{}, error) {
+	n := len(outs)
+	var err error
+	if returnsError(meta) {
+		n--
+		if e := outs[n]; !e.IsNil() {
+			err = e.Interface().(error)
+		}
+	}
+	results := make([]interface{}, n)
+	for i := 0; i < n; i++ {
+		results[i] = outs[i].Interface()
+	}
+	return results, err
+}
+
+// joinResults turns an interceptor chain's outcome back into the method's return values.
+func joinResults(meta MethodMeta, results []interface{}, err error) ([]reflect.Value, error) {
+	hasError := returnsError(meta)
+	n := len(meta.OutTypes)
+	if hasError {
+		n--
+	}
+
+	outs := make([]reflect.Value, len(meta.OutTypes))
+	for i := 0; i < n; i++ {
+		var v interface{}
+		if i < len(results) {
+			v = results[i]
+		}
+		rv, convErr := valueAs(v, meta.OutTypes[i])
+		if convErr != nil {
+			return nil, fmt.Errorf("result %d: %w", i, convErr)
+		}
+		outs[i] = rv
+	}
+
+	if !hasError {
+		return outs, err
+	}
+	errVal := reflect.New(meta.OutTypes[n]).Elem()
+	if err != nil {
+		errVal.Set(reflect.ValueOf(err))
+	}
+	outs[n] = errVal
+	return outs, nil
+}
+
+// valueAs converts v to a reflect.Value of type t; nil becomes the zero value.
+func valueAs(v interface{}, t reflect.Type) (reflect.Value, error) {
+	if v == nil {
+		return reflect.Zero(t), nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Type().AssignableTo(t) {
+		out := reflect.New(t).Elem()
+		out.Set(rv)
+		return out, nil
+	}
+	return reflect.Value{}, fmt.Errorf("cannot use %s as %s", rv.Type(), t)
+}
+
+func returnsError(meta MethodMeta) bool {
+	n := len(meta.OutTypes)
+	return n > 0 && meta.OutTypes[n-1].String() == "error"
+}
@@ -0,0 +1,197 @@
+package register
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// tokenBucket admits rate calls per second on average and up to burst at once.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// refill adds the tokens earned since the last call; b.mu must be held.
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// takeAll consumes a token from every bucket, or none of them and reports how long until
+// all have one. Buckets are locked in the order given, which callers keep method first.
+func takeAll(buckets []*tokenBucket) (bool, time.Duration) {
+	for _, b := range buckets {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+	}
+	now := time.Now()
+	var wait time.Duration
+	for _, b := range buckets {
+		b.refill(now)
+		if b.tokens < 1 {
+			wait = max(wait, time.Duration((1-b.tokens)/b.rate*float64(time.Second)))
+		}
+	}
+	if wait > 0 {
+		return false, wait
+	}
+	for _, b := range buckets {
+		b.tokens--
+	}
+	return true, 0
+}
+
+// limiter caps the calls of a service or of one method.
+type limiter struct {
+	bucket      *tokenBucket // nil: no rate limit
+	maxInFlight int64        // 0: no concurrency cap
+	inFlight    atomic.Int64
+}
+
+func (l *limiter) enter() bool {
+	if l.maxInFlight <= 0 {
+		return true
+	}
+	if l.inFlight.Add(1) > l.maxInFlight {
+		l.inFlight.Add(-1)
+		return false
+	}
+	return true
+}
+
+func (l *limiter) leave() {
+	if l.maxInFlight > 0 {
+		l.inFlight.Add(-1)
+	}
+}
+
+// RateLimit(100, 20) admits 100 calls per second to the service on average, bursting to 20.
+// Method limits apply on top of it.
+func (s *ServerCreator) RateLimit(perSecond float64, burst int) *ServerCreator {
+	if s.srv != nil && s.checkRate("rate limit", perSecond, burst) {
+		s.serviceLimiter().bucket = newTokenBucket(perSecond, burst)
+	}
+	return s
+}
+
+// MaxConcurrency(50) caps the calls served at once across all methods of the service.
+func (s *ServerCreator) MaxConcurrency(n int) *ServerCreator {
+	if s.srv != nil && s.checkConcurrency("max concurrency", n) {
+		s.serviceLimiter().maxInFlight = int64(n)
+	}
+	return s
+}
+
+// MethodRateLimit("Search", 10, 5) rate limits one registered method.
+func (s *ServerCreator) MethodRateLimit(name string, perSecond float64, burst int) *ServerCreator {
+	if !s.checkRate("rate limit of "+name, perSecond, burst) {
+		return s
+	}
+	if l := s.methodLimiter(name, "rate limit"); l != nil {
+		l.bucket = newTokenBucket(perSecond, burst)
+	}
+	return s
+}
+
+// MethodMaxConcurrency("Export", 2) caps the calls of one registered method served at once.
+func (s *ServerCreator) MethodMaxConcurrency(name string, n int) *ServerCreator {
+	if !s.checkConcurrency("max concurrency of "+name, n) {
+		return s
+	}
+	if l := s.methodLimiter(name, "max concurrency"); l != nil {
+		l.maxInFlight = int64(n)
+	}
+	return s
+}
+
+func (s *ServerCreator) checkRate(what string, perSecond float64, burst int) bool {
+	if !(perSecond > 0) || math.IsInf(perSecond, 1) || burst < 1 {
+		s.error = fmt.Errorf("set %s: rate %v and burst %d must be positive", what, perSecond, burst)
+		return false
+	}
+	return true
+}
+
+func (s *ServerCreator) checkConcurrency(what string, n int) bool {
+	if n < 1 {
+		s.error = fmt.Errorf("set %s: %d must be at least 1", what, n)
+		return false
+	}
+	return true
+}
+
+func (s *ServerCreator) serviceLimiter() *limiter {
+	if s.srv.limit == nil {
+		s.srv.limit = &limiter{}
+	}
+	return s.srv.limit
+}
+
+func (s *ServerCreator) methodLimiter(name, what string) *limiter {
+	if _, ok := s.srv.MethodMeta[name]; !ok {
+		s.error = fmt.Errorf("set %s: method %s not registered on %s", what, name, s.srv.ServiceName)
+		return nil
+	}
+	if s.srv.methodLimits == nil {
+		s.srv.methodLimits = map[string]*limiter{}
+	}
+	if s.srv.methodLimits[name] == nil {
+		s.srv.methodLimits[name] = &limiter{}
+	}
+	return s.srv.methodLimits[name]
+}
+
+// Acquire admits a call of method under the method and service limits. On success release
+// must be called once the call ends. Otherwise it returns a retryable CodeOverloaded error
+// and, for rate limits, how long until the call would be admitted.
+func (s *ServerRegister) Acquire(method string) (release func(), retryAfter time.Duration, err *Error) {
+	var limits []*limiter
+	if l := s.methodLimits[method]; l != nil {
+		limits = append(limits, l)
+	}
+	if s.limit != nil {
+		limits = append(limits, s.limit)
+	}
+
+	entered := 0
+	release = func() {
+		for _, l := range limits[:entered] {
+			l.leave()
+		}
+	}
+	for _, l := range limits {
+		if !l.enter() {
+			release()
+			return nil, 0, s.overloaded(method, "concurrency")
+		}
+		entered++
+	}
+	var buckets []*tokenBucket
+	for _, l := range limits {
+		if l.bucket != nil {
+			buckets = append(buckets, l.bucket)
+		}
+	}
+	// a call refused by one bucket must not spend the tokens of the others
+	if ok, wait := takeAll(buckets); !ok {
+		release()
+		return nil, wait, s.overloaded(method, "rate").WithDetail("retry_after_ms", wait.Milliseconds())
+	}
+	return release, 0, nil
+}
+
+func (s *ServerRegister) overloaded(method, limit string) *Error {
+	return Errorf(CodeOverloaded, "%s.%s is over its %s limit", s.ServiceName, method, limit).
+		WithDetail("service", s.ServiceName).WithDetail("method", method).WithDetail("limit", limit).
+		WithRetryable(true)
+}
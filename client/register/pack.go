@@ -1,12 +1,15 @@
 package register
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"github.com/goccy/go-json"
 	"reflect"
 )
 
+// WrappedRequest and WrappedResponse are the envelopes for every codec. With a binary
+// codec the json.RawMessage values hold bytes in that codec rather than JSON.
 type WrappedRequest struct {
 	Args map[string]json.RawMessage `json:"args"`
 }
@@ -14,25 +17,63 @@ type WrappedRequest struct {
 type WrappedResponse struct {
 	Resp  map[string]json.RawMessage `json:"resp"`
 	Error string                     `json:"error"`
+	// ErrorInfo is set for structured errors; Error always keeps the plain message for older SDKs.
+	ErrorInfo *Error `json:"error_info,omitempty"`
 }
 
 func PackRequest(meta MethodMeta, args []reflect.Value) ([]byte, error) {
+	return PackRequestWith(JSONCodec, meta, args)
+}
+
+// PackRequestWith is PackRequest for the given codec.
+func PackRequestWith(codec Codec, meta MethodMeta, args []reflect.Value) ([]byte, error) {
 	w := WrappedRequest{Args: map[string]json.RawMessage{}}
 
 	for i, v := range args {
-		b, err := json.Marshal(v.Interface())
+		b, err := codec.Marshal(v.Interface())
 		if err != nil {
 			return nil, err
 		}
 		w.Args[fmt.Sprintf("arg%d", i)] = b
 	}
 
-	return json.Marshal(w)
+	return codec.Marshal(w)
 }
 
+// UnpackRequest decodes the call arguments from body. Accepted shapes:
+//   - {"args": {"arg0": ..., "arg1": ...}}        positional (what PackRequest produces)
+//   - {"args": {"req": ..., "page": ...}}         keyed by registered argument names
+//   - {"req": ..., "page": ...}                   names at the top level
+//   - {...}                                       bare body, for single-argument methods
+//
+// Every argument must be present and every key must address an argument; a missing or
+// misspelled one fails the call instead of reaching the handler as a zero value.
 func UnpackRequest(meta MethodMeta, body []byte, ctxVal reflect.Value) ([]reflect.Value, error) {
-	var w WrappedRequest
-	if err := json.Unmarshal(body, &w); err != nil {
+	return UnpackRequestWith(JSONCodec, meta, body, ctxVal)
+}
+
+// UnpackRequestWith is UnpackRequest for the given codec. The top-level and bare-body
+// shapes are JSON only; binary codecs always use the args envelope.
+func UnpackRequestWith(codec Codec, meta MethodMeta, body []byte, ctxVal reflect.Value) ([]reflect.Value, error) {
+	var raws map[string]json.RawMessage
+	if codec == JSONCodec {
+		var err error
+		if raws, err = requestArgs(meta, body); err != nil {
+			return nil, err
+		}
+	} else if len(body) > 0 {
+		var w WrappedRequest
+		if err := codec.Unmarshal(body, &w); err != nil {
+			return nil, err
+		}
+		raws = w.Args
+	}
+
+	argCount := len(meta.InTypes)
+	if meta.HasCtx {
+		argCount--
+	}
+	if err := checkArgKeys(meta, raws, argCount); err != nil {
 		return nil, err
 	}
 
@@ -46,12 +87,14 @@ func UnpackRequest(meta MethodMeta, body []byte, ctxVal reflect.Value) ([]reflec
 		if i == 0 && meta.HasCtx {
 			continue
 		}
-		key := fmt.Sprintf("arg%d", argIndex)
-		raw := w.Args[key]
+		raw := lookupArg(meta, raws, argIndex)
+		if len(raw) == 0 {
+			return nil, fmt.Errorf("missing arg %s", argLabel(meta, argIndex))
+		}
 
 		ptr := reflect.New(meta.InTypes[i])
-		if err := json.Unmarshal(raw, ptr.Interface()); err != nil {
-			return nil, err
+		if err := codec.Unmarshal(raw, ptr.Interface()); err != nil {
+			return nil, fmt.Errorf("arg %s: %w", argLabel(meta, argIndex), err)
 		}
 
 		inVals = append(inVals, ptr.Elem())
@@ -61,8 +104,130 @@ func UnpackRequest(meta MethodMeta, body []byte, ctxVal reflect.Value) ([]reflec
 	return inVals, nil
 }
 
+// requestArgs normalizes every accepted request shape into a key -> raw argument map.
+func requestArgs(meta MethodMeta, body []byte) (map[string]json.RawMessage, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return map[string]json.RawMessage{}, nil
+	}
+
+	argCount := len(meta.ArgNames)
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(body, &top); err != nil {
+		// not an object (e.g. a bare slice or scalar): only meaningful for a single argument
+		if argCount == 1 {
+			return map[string]json.RawMessage{"arg0": body}, nil
+		}
+		return nil, err
+	}
+
+	// Wrapped envelope takes precedence so existing SDKs keep working.
+	if rawArgs, ok := top["args"]; ok && !isArgName(meta, "args") {
+		var args map[string]json.RawMessage
+		if err := json.Unmarshal(rawArgs, &args); err == nil {
+			for key := range top {
+				if key != "args" {
+					return nil, fmt.Errorf("unknown key %q next to args", key)
+				}
+			}
+			return args, nil
+		}
+	}
+
+	if argCount == 1 && !onlyArgKeys(meta, top) {
+		return map[string]json.RawMessage{"arg0": body}, nil
+	}
+	return top, nil
+}
+
+// lookupArg finds argument i by positional key first, then by its registered name.
+func lookupArg(meta MethodMeta, raws map[string]json.RawMessage, i int) json.RawMessage {
+	if raw, ok := raws[fmt.Sprintf("arg%d", i)]; ok {
+		return raw
+	}
+	if i < len(meta.ArgNames) {
+		return raws[meta.ArgNames[i]]
+	}
+	return nil
+}
+
+// checkArgKeys rejects keys that address no argument, such as a misspelled name or a
+// position past the last argument.
+func checkArgKeys(meta MethodMeta, raws map[string]json.RawMessage, argCount int) error {
+	for key := range raws {
+		if idx, ok := argPosition(key); ok && idx < argCount {
+			continue
+		}
+		if !isArgName(meta, key) {
+			return fmt.Errorf("unknown arg %q", key)
+		}
+	}
+	return nil
+}
+
+// argPosition parses a positional key "argN".
+func argPosition(key string) (int, bool) {
+	var idx int
+	if n, err := fmt.Sscanf(key, "arg%d", &idx); err != nil || n != 1 || fmt.Sprintf("arg%d", idx) != key {
+		return 0, false
+	}
+	return idx, true
+}
+
+func isArgName(meta MethodMeta, key string) bool {
+	for _, name := range meta.ArgNames {
+		if name == key {
+			return true
+		}
+	}
+	return false
+}
+
+// onlyArgKeys reports whether every key of top addresses an argument (by position or name).
+func onlyArgKeys(meta MethodMeta, top map[string]json.RawMessage) bool {
+	if len(top) == 0 {
+		return false
+	}
+	for key := range top {
+		if isArgName(meta, key) {
+			continue
+		}
+		if _, ok := argPosition(key); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func argLabel(meta MethodMeta, i int) string {
+	if i < len(meta.ArgNames) && meta.ArgNames[i] != "" {
+		return meta.ArgNames[i]
+	}
+	return fmt.Sprintf("arg%d", i)
+}
+
 func PackResponse(meta MethodMeta, results []reflect.Value) ([]byte, error) {
-	resp := WrappedResponse{
+	return PackResponseWith(JSONCodec, meta, results)
+}
+
+// PackResponseWith is PackResponse for the given codec.
+func PackResponseWith(codec Codec, meta MethodMeta, results []reflect.Value) ([]byte, error) {
+	resp, err := NewResponseWith(codec, meta, results)
+	if err != nil {
+		return nil, err
+	}
+	return codec.Marshal(resp)
+}
+
+// NewResponse builds the response envelope for results without serializing it,
+// so transports can still annotate it (e.g. with a trace ID) before writing.
+func NewResponse(meta MethodMeta, results []reflect.Value) (*WrappedResponse, error) {
+	return NewResponseWith(JSONCodec, meta, results)
+}
+
+// NewResponseWith is NewResponse with results encoded by codec; marshal the envelope with the same codec.
+func NewResponseWith(codec Codec, meta MethodMeta, results []reflect.Value) (*WrappedResponse, error) {
+	resp := &WrappedResponse{
 		Resp: map[string]json.RawMessage{},
 	}
 
@@ -70,7 +235,7 @@ func PackResponse(meta MethodMeta, results []reflect.Value) ([]byte, error) {
 
 	// --- Case 1: no return values ---
 	if numOut == 0 {
-		return json.Marshal(resp)
+		return resp, nil
 	}
 
 	lastT := meta.OutTypes[numOut-1]
@@ -80,34 +245,60 @@ func PackResponse(meta MethodMeta, results []reflect.Value) ([]byte, error) {
 	if hasError {
 		errVal := results[numOut-1]
 		if !errVal.IsNil() {
-			resp.Error = errVal.Interface().(error).Error()
+			err := errVal.Interface().(error)
+			resp.Error = err.Error()
+			resp.ErrorInfo = errorInfo(err)
 		}
 		// Pack normal return values before the error
 		for i := 0; i < numOut-1; i++ {
-			b, err := json.Marshal(results[i].Interface())
+			b, err := codec.Marshal(results[i].Interface())
 			if err != nil {
 				return nil, err
 			}
 			resp.Resp[fmt.Sprintf("resp%d", i)] = b
 		}
-		return json.Marshal(resp)
+		return resp, nil
 	}
 
 	// --- Case 3: no error return values ---
 	for i := 0; i < numOut; i++ {
-		b, err := json.Marshal(results[i].Interface())
+		b, err := codec.Marshal(results[i].Interface())
 		if err != nil {
 			return nil, err
 		}
 		resp.Resp[fmt.Sprintf("resp%d", i)] = b
 	}
 
-	return json.Marshal(resp)
+	return resp, nil
+}
+
+// ErrorResponse builds the envelope for a call that failed before or outside the handler.
+func ErrorResponse(e *Error) *WrappedResponse {
+	return &WrappedResponse{
+		Resp:      map[string]json.RawMessage{},
+		Error:     e.Error(),
+		ErrorInfo: e,
+	}
+}
+
+// FailedResponse is the envelope for a call stopped with err when the method itself
+// has no error return to carry it (e.g. rejected by an interceptor).
+func FailedResponse(err error) *WrappedResponse {
+	return &WrappedResponse{
+		Resp:      map[string]json.RawMessage{},
+		Error:     err.Error(),
+		ErrorInfo: errorInfo(err),
+	}
 }
 
 func UnpackResponse(meta MethodMeta, body []byte) ([]reflect.Value, error) {
+	return UnpackResponseWith(JSONCodec, meta, body)
+}
+
+// UnpackResponseWith is UnpackResponse for the given codec.
+func UnpackResponseWith(codec Codec, meta MethodMeta, body []byte) ([]reflect.Value, error) {
 	var w WrappedResponse
-	if err := json.Unmarshal(body, &w); err != nil {
+	if err := codec.Unmarshal(body, &w); err != nil {
 		return nil, err
 	}
 
@@ -125,17 +316,23 @@ func UnpackResponse(meta MethodMeta, body []byte) ([]reflect.Value, error) {
 		// Normal return values
 		for i := 0; i < numOut-1; i++ {
 			ptr := reflect.New(meta.OutTypes[i])
-			if err := json.Unmarshal(w.Resp[fmt.Sprintf("resp%d", i)], ptr.Interface()); err != nil {
+			if err := codec.Unmarshal(w.Resp[fmt.Sprintf("resp%d", i)], ptr.Interface()); err != nil {
 				return nil, err
 			}
 			outVals[i] = ptr.Elem()
 		}
 
-		// error
-		if w.Error == "" {
-			outVals[numOut-1] = reflect.Zero(reflect.TypeOf((*error)(nil)).Elem())
-		} else {
+		// error: structured when the node sent ErrorInfo, plain otherwise
+		switch {
+		case w.ErrorInfo != nil:
+			if w.ErrorInfo.Message == "" {
+				w.ErrorInfo.Message = w.Error
+			}
+			outVals[numOut-1] = reflect.ValueOf(w.ErrorInfo)
+		case w.Error != "":
 			outVals[numOut-1] = reflect.ValueOf(errors.New(w.Error))
+		default:
+			outVals[numOut-1] = reflect.Zero(reflect.TypeOf((*error)(nil)).Elem())
 		}
 		return outVals, nil
 	}
@@ -143,7 +340,7 @@ func UnpackResponse(meta MethodMeta, body []byte) ([]reflect.Value, error) {
 	// No error
 	for i := 0; i < numOut; i++ {
 		ptr := reflect.New(meta.OutTypes[i])
-		if err := json.Unmarshal(w.Resp[fmt.Sprintf("resp%d", i)], ptr.Interface()); err != nil {
+		if err := codec.Unmarshal(w.Resp[fmt.Sprintf("resp%d", i)], ptr.Interface()); err != nil {
 			return nil, err
 		}
 		outVals[i] = ptr.Elem()
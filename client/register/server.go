@@ -11,6 +11,8 @@ import (
 	"reflect"
 	"regexp"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type ServerName = string
@@ -29,17 +31,31 @@ type MethodMeta struct {
 	OutTypes []reflect.Type // includes error
 
 	ArgNames []string // inferred + user supplied
+
+	Timeout time.Duration // per-call budget; 0 falls back to the service timeout
+	Allow   []string      // caller IDs admitted by Authorize; nil falls back to the service list
 }
 
 type ServerRegister struct {
-	ServiceName ServerName
-	Host        string
-	Version     string
-	Environment string
-	Apis        []ApiInfo `json:"apis"`
-	FnMap       map[string]reflect.Value
-	MethodMeta  map[string]MethodMeta `json:"-"`
-	created     bool                  // whether Create() has been called
+	ServiceName  ServerName
+	Host         string
+	Version      string
+	Environment  string
+	Apis         []ApiInfo `json:"apis"`
+	FnMap        map[string]reflect.Value
+	MethodMeta   map[string]MethodMeta `json:"-"`
+	timeout      time.Duration         // default per-call budget for methods without their own
+	allow        []string              // default Allow list for methods without their own
+	limit        *limiter              // service-wide rate and concurrency limits, see Acquire
+	methodLimits map[string]*limiter   // per-method limits, applied on top of limit
+	interceptors []Interceptor         // service interceptors, run after the global ones
+	healthChecks []func(ctx context.Context) error
+	health       healthState // cached result of healthChecks, see Health
+	created      bool        // whether Create() has been called
+	registered   atomic.Bool // whether the hub currently has this service registered
+	deregistered atomic.Bool // taken out of rotation on purpose; suppresses re-registration
+	retrying     atomic.Bool // a background registration retry is running
+	hub          hubState    // latest registration/heartbeat outcome, see Status
 }
 
 type ServerCreator struct {
@@ -130,6 +146,35 @@ func (s *ServerCreator) Env(env string) *ServerCreator {
 	return s
 }
 
+// Timeout(3 * time.Second) bounds every call to the service unless the method sets its own.
+// The handler's context ends at this budget or the caller's deadline, whichever is sooner.
+func (s *ServerCreator) Timeout(d time.Duration) *ServerCreator {
+	if s.srv != nil {
+		s.srv.timeout = d
+	}
+	return s
+}
+
+// MethodTimeout("Login", time.Second) overrides the service timeout for one registered method.
+func (s *ServerCreator) MethodTimeout(name string, d time.Duration) *ServerCreator {
+	meta, ok := s.srv.MethodMeta[name]
+	if !ok {
+		s.error = fmt.Errorf("set timeout: method %s not registered on %s", name, s.srv.ServiceName)
+		return s
+	}
+	meta.Timeout = d
+	s.srv.MethodMeta[name] = meta
+	return s
+}
+
+// TimeoutOf returns the budget applied to calls of method, or 0 when unbounded.
+func (s *ServerRegister) TimeoutOf(method string) time.Duration {
+	if d := s.MethodMeta[method].Timeout; d > 0 {
+		return d
+	}
+	return s.timeout
+}
+
 func (c *ServerCreator) Reg(fn interface{}) *ServerCreator {
 	name := utils.GetFuncName(fn)
 	return c.RegName(name, fn)
@@ -158,11 +203,18 @@ func Start() error {
 	if gncfg.Cfg.HubAddr == "" {
 		return errors.New("HubAddr not set via UseConfig")
 	}
+	if err := gncfg.Cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid gn config: %w", err)
+	}
+	if _, err := hubTransport(gncfg.Current()); err != nil {
+		return fmt.Errorf("invalid gn config: %w", err)
+	}
 
 	localIP := autoDetectIP()
 	if localIP == "" && gncfg.Cfg.NodeAddr == "" {
 		return errors.New("failed to detect local IP; ensure network is OK or set Host manually")
 	}
+	startHealthLoop()
 
 	var firstErr error
 	var hasRegistered bool
@@ -183,22 +235,93 @@ func Start() error {
 			}
 		}
 
-		if err := sendRegisterWithTimeout(gncfg.Cfg.HubAddr, srv); err != nil {
+		srv.deregistered.Store(false)
+		if err := registerOnce(srv); err != nil {
 			if firstErr == nil {
 				firstErr = err
 			}
-			logger.Error(context.Background(), "report to registry failed", zap.String("service", srv.ServiceName), zap.String("hub", gncfg.Cfg.HubAddr), zap.Error(err))
+			// keep trying in the background; the heartbeat loop starts once it succeeds
+			scheduleRegisterRetry(srv)
 			return true
 		}
 
 		hasRegistered = true
-		logger.Info(context.Background(), "report to registry succeeded", zap.String("service", srv.ServiceName), zap.String("hub", gncfg.Cfg.HubAddr), zap.String("host", srv.Host))
 		return true
 	})
 
 	if hasRegistered {
-		startHeartbeatLoop(gncfg.Cfg.HubAddr)
+		startHeartbeatLoop()
 	}
 
 	return firstErr
 }
+
+// Deregister takes a service out of the hub's rotation without stopping the process.
+// Heartbeats for the service stop until Start registers it again.
+func Deregister(name ServerName) error {
+	val, ok := registeredServers.Load(name)
+	if !ok {
+		return fmt.Errorf("service %s not found", name)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), hubTimeout())
+	defer cancel()
+	return deregister(ctx, val.(*ServerRegister))
+}
+
+// DeregisterAll deregisters every registered service, bounded by ctx and the hub request timeout.
+// It is used on graceful shutdown so the hub stops routing to this node right away.
+func DeregisterAll(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, hubTimeout())
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	registeredServers.Range(func(_, value interface{}) bool {
+		srv := value.(*ServerRegister)
+		if !srv.registered.Load() {
+			return true
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := deregister(ctx, srv); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}()
+		return true
+	})
+	wg.Wait()
+	return firstErr
+}
+
+func deregister(ctx context.Context, srv *ServerRegister) error {
+	if gncfg.Cfg.HubAddr == "" {
+		return errors.New("HubAddr not set via UseConfig")
+	}
+	// Stop heartbeats and retries first so they cannot re-announce the service while it is leaving.
+	srv.deregistered.Store(true)
+	hubNodes.Range(func(_, value interface{}) bool {
+		value.(*hubNode).releasePending(srv.ServiceName, true)
+		return true
+	})
+	if !srv.registered.Swap(false) {
+		return nil
+	}
+	// every hub may hold the registration, whatever the policy was when it was sent
+	err := eachHub(ctx, gncfg.HubPolicyAll, func(h *hubNode) error {
+		return sendDeregister(ctx, h.addr, srv)
+	})
+	if err != nil {
+		logger.Error(context.Background(), "deregister from registry failed", zap.String("service", srv.ServiceName), zap.String("hub", gncfg.Cfg.HubAddr), zap.Error(err))
+		return err
+	}
+	logger.Info(context.Background(), "deregister from registry succeeded", zap.String("service", srv.ServiceName), zap.String("hub", gncfg.Cfg.HubAddr), zap.String("host", srv.Host))
+	return nil
+}
@@ -0,0 +1,79 @@
+package register
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// ServiceStatus is what the node believes about one of its services.
+type ServiceStatus struct {
+	Service            string    `json:"service"`
+	Host               string    `json:"host"`
+	Version            string    `json:"version,omitempty"`
+	Env                string    `json:"env,omitempty"`
+	Created            bool      `json:"created"`
+	Registered         bool      `json:"registered"`
+	Deregistered       bool      `json:"deregistered"`
+	Retrying           bool      `json:"retrying"`
+	Methods            int       `json:"methods"`
+	LastRegistered     time.Time `json:"last_registered,omitempty"`
+	LastHeartbeat      time.Time `json:"last_heartbeat,omitempty"`
+	LastHeartbeatError string    `json:"last_heartbeat_error,omitempty"`
+}
+
+// hubState records the outcome of the latest exchanges with the hub for one service.
+type hubState struct {
+	mu               sync.Mutex
+	lastRegistered   time.Time
+	lastHeartbeat    time.Time
+	lastHeartbeatErr string
+}
+
+func (h *hubState) recordRegistered() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastRegistered = time.Now()
+}
+
+func (h *hubState) recordHeartbeat(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastHeartbeat = time.Now()
+	h.lastHeartbeatErr = ""
+	if err != nil {
+		h.lastHeartbeatErr = err.Error()
+	}
+}
+
+// Status returns a snapshot of the service's registration state.
+func (s *ServerRegister) Status() ServiceStatus {
+	st := ServiceStatus{
+		Service:      s.ServiceName,
+		Host:         s.Host,
+		Version:      s.Version,
+		Env:          s.Environment,
+		Created:      s.created,
+		Registered:   s.registered.Load(),
+		Deregistered: s.deregistered.Load(),
+		Retrying:     s.retrying.Load(),
+		Methods:      len(s.Apis),
+	}
+	s.hub.mu.Lock()
+	st.LastRegistered = s.hub.lastRegistered
+	st.LastHeartbeat = s.hub.lastHeartbeat
+	st.LastHeartbeatError = s.hub.lastHeartbeatErr
+	s.hub.mu.Unlock()
+	return st
+}
+
+// Statuses returns the status of every service known to the node, sorted by name.
+func Statuses() []ServiceStatus {
+	var result []ServiceStatus
+	registeredServers.Range(func(_, value interface{}) bool {
+		result = append(result, value.(*ServerRegister).Status())
+		return true
+	})
+	sort.Slice(result, func(i, j int) bool { return result[i].Service < result[j].Service })
+	return result
+}
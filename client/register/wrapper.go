@@ -105,13 +105,32 @@ func makeWrapper(service, method string, fn interface{}, userArgNames []string)
 
 	meta.ArgNames = make([]string, argCount)
 
+	// a request addresses arguments by name, so every name must be unique
+	used := map[string]bool{}
+	for i := 0; i < argCount && i < len(userArgNames); i++ {
+		name := userArgNames[i]
+		if name == "" {
+			continue
+		}
+		if used[name] {
+			err = fmt.Errorf("%s.%s duplicate arg name %q", service, method, name)
+			return
+		}
+		used[name] = true
+		meta.ArgNames[i] = name
+	}
 	for i := 0; i < argCount; i++ {
-		if i < len(userArgNames) && userArgNames[i] != "" {
-			meta.ArgNames[i] = userArgNames[i]
-		} else {
-			paramType := t.In(i + boolToInt(meta.HasCtx))
-			meta.ArgNames[i] = autoArgName(paramType)
+		if meta.ArgNames[i] != "" {
+			continue
+		}
+		// inferred names repeat for arguments of one type: string, string1, string2...
+		base := autoArgName(t.In(i + boolToInt(meta.HasCtx)))
+		name := base
+		for n := 1; used[name]; n++ {
+			name = fmt.Sprintf("%s%d", base, n)
 		}
+		used[name] = true
+		meta.ArgNames[i] = name
 	}
 
 	// 2. Fill ApiInfo.Args
@@ -159,6 +178,13 @@ func makeWrapper(service, method string, fn interface{}, userArgNames []string)
 	return
 }
 
+// MetaOf builds the MethodMeta of fn without registering it, so callers that
+// only know a signature (e.g. outbound stubs) can pack and unpack calls.
+func MetaOf(service, method string, fn interface{}, argNames ...string) (MethodMeta, error) {
+	meta, _, err := makeWrapper(service, method, fn, argNames)
+	return meta, err
+}
+
 func typeList(t reflect.Type) []reflect.Type {
 	arr := make([]reflect.Type, t.NumOut())
 	for i := 0; i < t.NumOut(); i++ {
@@ -1,29 +1,197 @@
 package gncfg
 
 import (
+	"errors"
+	"fmt"
 	configure "github.com/jom-io/gorig/utils/cofigure"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
 const (
 	DefNodePort = ":5807"
+
+	DefHeartbeatInterval = time.Minute
+	DefHubTimeout        = 5 * time.Second
+	DefHealthInterval    = 10 * time.Second
+	DefRetryMin          = time.Second
+	DefRetryMax          = time.Minute
+	DefAdaptiveMaxLimit  = 1000
+
+	// HubPolicyAll registers and heartbeats with every hub in HubAddr.
+	HubPolicyAll = "all"
+	// HubPolicyFailover talks to the first reachable hub in HubAddr order only.
+	HubPolicyFailover = "failover"
 )
 
 type GlobalConfig struct {
-	HubAddr  string
-	NodeAddr string
+	// HubAddr is a comma-separated list of hubs. An entry may also be dns://host:port, expanded to
+	// every address of host, or srv://name, expanded to the targets of the SRV record name.
+	HubAddr   string
+	HubPolicy string // gn.hub.policy: HubPolicyAll (default) or HubPolicyFailover
+	NodeAddr  string
+
+	// Zero values below fall back to the Def* constants, see WithDefaults.
+	HeartbeatInterval time.Duration // gn.heartbeat.interval
+	HeartbeatJitter   time.Duration // gn.heartbeat.jitter: random extra delay per beat, below the interval
+	HubTimeout        time.Duration // gn.hub.timeout: bound of a single hub request
+	RetryMin          time.Duration // gn.register.retry.min: first registration retry delay, doubled after each failure
+	RetryMax          time.Duration // gn.register.retry.max: cap of the retry delay
+	RetryLimit        int           // gn.register.retry.limit: attempts before giving up; 0 retries until stopped
+	HealthInterval    time.Duration // gn.health.interval: how often service health checks run in the background
+
+	// Hub authentication; every part is optional and they can be combined.
+	HubToken    string // gn.hub.token: sent as a bearer token on every hub request
+	HubSecret   string // gn.hub.secret: signs every hub request with HMAC-SHA256, see register.HubSignature
+	HubCertFile string // gn.hub.tls.cert: client certificate presented to the hub
+	HubKeyFile  string // gn.hub.tls.key: key of HubCertFile
+	HubCAFile   string // gn.hub.tls.ca: CA bundle verifying the hub instead of the system roots
+
+	// Inbound TLS: the node serves https once TLSCertFile and TLSKeyFile are set.
+	TLSCertFile     string // gn.tls.cert: reloaded from disk when the file changes
+	TLSKeyFile      string // gn.tls.key
+	TLSClientCAFile string // gn.tls.client_ca: service calls and /_gn endpoints must present a certificate signed by this bundle; reloaded on change
+	TLSMinVersion   string // gn.tls.min_version: "1.2" (default) or "1.3"
+
+	// Adaptive load shedding: learn a node-wide concurrency limit from handler latency and
+	// turn away calls above it. The limit is shared by all services of the node.
+	AdaptiveLimit    bool // gn.limit.adaptive
+	AdaptiveMaxLimit int  // gn.limit.adaptive.max: ceiling of the learned limit
 }
 
-var Cfg GlobalConfig
+var (
+	Cfg     GlobalConfig
+	current atomic.Pointer[GlobalConfig]
+)
 
 func UseConfig(cfg GlobalConfig) {
 	Cfg = cfg
+	current.Store(&cfg)
 }
 
-func init() {
-	hub := configure.GetString("gn.hub.addr", "")
-	node := configure.GetString("gn.node.addr", "")
-	Cfg = GlobalConfig{
-		HubAddr:  hub,
-		NodeAddr: node,
+// Current returns the config last passed to UseConfig (or loaded from gn.* keys) with
+// defaults applied. Unlike Cfg it is safe to read from background goroutines.
+func Current() GlobalConfig {
+	if c := current.Load(); c != nil {
+		return c.WithDefaults()
+	}
+	return GlobalConfig{}.WithDefaults()
+}
+
+// WithDefaults returns a copy of c with unset settings replaced by the defaults.
+func (c GlobalConfig) WithDefaults() GlobalConfig {
+	if c.HeartbeatInterval == 0 {
+		c.HeartbeatInterval = DefHeartbeatInterval
 	}
+	if c.HubTimeout == 0 {
+		c.HubTimeout = DefHubTimeout
+	}
+	if c.HealthInterval == 0 {
+		c.HealthInterval = DefHealthInterval
+	}
+	if c.RetryMin == 0 {
+		c.RetryMin = DefRetryMin
+	}
+	if c.RetryMax == 0 {
+		c.RetryMax = max(DefRetryMax, c.RetryMin)
+	}
+	if c.HubPolicy == "" {
+		c.HubPolicy = HubPolicyAll
+	}
+	if c.AdaptiveMaxLimit == 0 {
+		c.AdaptiveMaxLimit = DefAdaptiveMaxLimit
+	}
+	return c
+}
+
+// HubTLS reports whether hub client TLS is configured. Hubs listed without a scheme are
+// then dialed over https.
+func (c GlobalConfig) HubTLS() bool {
+	return c.HubCertFile != "" || c.HubCAFile != ""
+}
+
+// InboundTLS reports whether the node serves its services over https.
+func (c GlobalConfig) InboundTLS() bool {
+	return c.TLSCertFile != ""
+}
+
+// Hubs splits HubAddr into its trimmed, non-empty entries.
+func (c GlobalConfig) Hubs() []string {
+	var hubs []string
+	for _, h := range strings.Split(c.HubAddr, ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			hubs = append(hubs, h)
+		}
+	}
+	return hubs
+}
+
+// Validate reports settings that cannot work together, after defaults are applied.
+func (c GlobalConfig) Validate() error {
+	c = c.WithDefaults()
+	var errs []error
+	if c.HeartbeatInterval < 0 {
+		errs = append(errs, fmt.Errorf("heartbeat interval %s must be positive", c.HeartbeatInterval))
+	}
+	if c.HeartbeatJitter < 0 || (c.HeartbeatInterval > 0 && c.HeartbeatJitter >= c.HeartbeatInterval) {
+		errs = append(errs, fmt.Errorf("heartbeat jitter %s must be in [0, %s)", c.HeartbeatJitter, c.HeartbeatInterval))
+	}
+	if c.HubTimeout < 0 {
+		errs = append(errs, fmt.Errorf("hub timeout %s must be positive", c.HubTimeout))
+	}
+	if c.HealthInterval < 0 {
+		errs = append(errs, fmt.Errorf("health interval %s must be positive", c.HealthInterval))
+	}
+	if c.RetryMin < 0 || c.RetryMax < c.RetryMin {
+		errs = append(errs, fmt.Errorf("register retry delays must satisfy 0 < min (%s) <= max (%s)", c.RetryMin, c.RetryMax))
+	}
+	if c.RetryLimit < 0 {
+		errs = append(errs, fmt.Errorf("register retry limit %d must not be negative", c.RetryLimit))
+	}
+	if (c.HubCertFile == "") != (c.HubKeyFile == "") {
+		errs = append(errs, errors.New("hub tls cert and key must be set together"))
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		errs = append(errs, errors.New("tls cert and key must be set together"))
+	}
+	if c.TLSClientCAFile != "" && c.TLSCertFile == "" {
+		errs = append(errs, errors.New("tls client CA needs a server cert and key"))
+	}
+	if c.TLSMinVersion != "" && c.TLSMinVersion != "1.2" && c.TLSMinVersion != "1.3" {
+		errs = append(errs, fmt.Errorf("tls min version %q must be 1.2 or 1.3", c.TLSMinVersion))
+	}
+	if c.AdaptiveMaxLimit < 0 {
+		errs = append(errs, fmt.Errorf("adaptive max limit %d must be positive", c.AdaptiveMaxLimit))
+	}
+	if c.HubPolicy != HubPolicyAll && c.HubPolicy != HubPolicyFailover {
+		errs = append(errs, fmt.Errorf("hub policy %q must be %q or %q", c.HubPolicy, HubPolicyAll, HubPolicyFailover))
+	}
+	return errors.Join(errs...)
+}
+
+func init() {
+	UseConfig(GlobalConfig{
+		HubAddr:           configure.GetString("gn.hub.addr", ""),
+		HubPolicy:         configure.GetString("gn.hub.policy", ""),
+		NodeAddr:          configure.GetString("gn.node.addr", ""),
+		HeartbeatInterval: configure.GetDuration("gn.heartbeat.interval", 0),
+		HeartbeatJitter:   configure.GetDuration("gn.heartbeat.jitter", 0),
+		HubTimeout:        configure.GetDuration("gn.hub.timeout", 0),
+		RetryMin:          configure.GetDuration("gn.register.retry.min", 0),
+		RetryMax:          configure.GetDuration("gn.register.retry.max", 0),
+		RetryLimit:        configure.GetInt("gn.register.retry.limit", 0),
+		HealthInterval:    configure.GetDuration("gn.health.interval", 0),
+		HubToken:          configure.GetString("gn.hub.token", ""),
+		HubSecret:         configure.GetString("gn.hub.secret", ""),
+		HubCertFile:       configure.GetString("gn.hub.tls.cert", ""),
+		HubKeyFile:        configure.GetString("gn.hub.tls.key", ""),
+		HubCAFile:         configure.GetString("gn.hub.tls.ca", ""),
+		TLSCertFile:       configure.GetString("gn.tls.cert", ""),
+		TLSKeyFile:        configure.GetString("gn.tls.key", ""),
+		TLSClientCAFile:   configure.GetString("gn.tls.client_ca", ""),
+		TLSMinVersion:     configure.GetString("gn.tls.min_version", ""),
+		AdaptiveLimit:     configure.GetBool("gn.limit.adaptive", false),
+		AdaptiveMaxLimit:  configure.GetInt("gn.limit.adaptive.max", 0),
+	})
 }
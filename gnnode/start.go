@@ -7,6 +7,7 @@ import (
 	"github.com/jom-io/gorig-node/client/register"
 	"github.com/jom-io/gorig-node/gncfg"
 	"github.com/jom-io/gorig/serv"
+	"github.com/jom-io/gorig/utils/errors"
 	"github.com/jom-io/gorig/utils/logger"
 	"github.com/jom-io/gorig/utils/sys"
 	"go.uber.org/zap"
@@ -47,6 +48,7 @@ func Startup(code, port string) error {
 		}
 		if err := inbound.StartInbound(port); err != nil {
 			logger.Error(context.Background(), "  * inbound service start failed: ", zap.Error(err))
+			sys.Exit(errors.Sys(err.Error()))
 		}
 	}()
 	return nil
@@ -54,9 +56,14 @@ func Startup(code, port string) error {
 
 func Shutdown(code string, ctx context.Context) error {
 	sys.Info("  * ", code, " service shutdown")
+	// Refuse new calls so callers move on, and leave the hub before waiting for calls in flight.
+	inbound.DrainInbound()
 	register.Stop()
-	if err := inbound.StopInbound(); err != nil {
-		return err
+	if err := register.DeregisterAll(ctx); err != nil {
+		logger.Error(ctx, "  * deregister services failed: ", zap.Error(err))
 	}
-	return nil
+	err := inbound.StopInboundContext(ctx)
+	// handlers in flight may still call other nodes, so discovery stops last
+	register.StopDiscovery()
+	return err
 }
@@ -0,0 +1,82 @@
+// Package gntrace carries OpenTelemetry spans across node calls using W3C traceparent headers.
+// Spans go to the global OpenTelemetry provider unless UseTracerProvider selects another
+// one; gntracetest records them in memory for tests.
+package gntrace
+
+import (
+	"context"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"sync"
+)
+
+const instrumentationName = "github.com/jom-io/gorig-node"
+
+// Span attribute keys set on call spans.
+const (
+	AttrService = attribute.Key("rpc.service")
+	AttrMethod  = attribute.Key("rpc.method")
+	AttrSystem  = attribute.Key("rpc.system")
+	AttrStatus  = attribute.Key("http.response.status_code")
+	AttrError   = attribute.Key("gn.error")
+)
+
+var (
+	mu       sync.RWMutex
+	provider trace.TracerProvider // nil means the global provider
+
+	propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+)
+
+// UseTracerProvider sends node spans to tp instead of the global provider.
+func UseTracerProvider(tp trace.TracerProvider) {
+	mu.Lock()
+	defer mu.Unlock()
+	provider = tp
+}
+
+// Tracer returns the tracer used for node spans.
+func Tracer() trace.Tracer {
+	mu.RLock()
+	tp := provider
+	mu.RUnlock()
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return tp.Tracer(instrumentationName)
+}
+
+// Extract returns ctx carrying the remote span context found in h, if any.
+func Extract(ctx context.Context, h http.Header) context.Context {
+	return propagator.Extract(ctx, propagation.HeaderCarrier(h))
+}
+
+// Inject writes the span context of ctx into h as traceparent (and baggage).
+func Inject(ctx context.Context, h http.Header) {
+	propagator.Inject(ctx, propagation.HeaderCarrier(h))
+}
+
+// StartCall starts a span for a call of service.method; kind tells server from client side.
+func StartCall(ctx context.Context, service, method string, kind trace.SpanKind) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, service+"/"+method,
+		trace.WithSpanKind(kind),
+		trace.WithAttributes(AttrSystem.String("gorig-node"), AttrService.String(service), AttrMethod.String(method)),
+	)
+}
+
+// EndCall records the outcome of a call span and ends it. errMsg is a business or transport
+// error message; status is the HTTP status when one was received, 0 otherwise.
+func EndCall(span trace.Span, status int, errMsg string) {
+	if status != 0 {
+		span.SetAttributes(AttrStatus.Int(status))
+	}
+	if errMsg != "" {
+		span.SetAttributes(AttrError.String(errMsg))
+		span.SetStatus(codes.Error, errMsg)
+	}
+	span.End()
+}
@@ -0,0 +1,17 @@
+// Package gntracetest records node spans in memory. It lives apart from gntrace so that
+// production binaries do not link the OpenTelemetry test exporter.
+package gntracetest
+
+import (
+	"github.com/jom-io/gorig-node/gntrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// UseInMemoryExporter records node spans in memory, for tests and local debugging.
+// gntrace.UseTracerProvider(nil) switches back to the global provider.
+func UseInMemoryExporter() *tracetest.InMemoryExporter {
+	exp := tracetest.NewInMemoryExporter()
+	gntrace.UseTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp)))
+	return exp
+}
@@ -0,0 +1,86 @@
+// Package metrics holds the node's Prometheus collectors. It uses its own registry so
+// embedding applications keep control over the default one.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"time"
+)
+
+// Call outcomes for the calls counter.
+const (
+	OutcomeOK             = "ok"
+	OutcomeBusinessError  = "business_error"  // the handler returned an error in the envelope
+	OutcomeTransportError = "transport_error" // the call was answered with a non-200 status
+)
+
+var (
+	Registry = prometheus.NewRegistry()
+
+	Calls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gn",
+		Name:      "calls_total",
+		Help:      "Inbound calls by service, method and outcome.",
+	}, []string{"service", "method", "outcome"})
+
+	CallDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "gn",
+		Name:      "call_duration_seconds",
+		Help:      "Inbound call latency by service and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"service", "method"})
+
+	InFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gn",
+		Name:      "calls_in_flight",
+		Help:      "Inbound calls currently being served.",
+	}, []string{"service", "method"})
+
+	Panics = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gn",
+		Name:      "handler_panics_total",
+		Help:      "Handler panics recovered by the dispatcher.",
+	}, []string{"service", "method"})
+
+	Heartbeats = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gn",
+		Name:      "hub_heartbeats_total",
+		Help:      "Heartbeat batches sent to the hub by result.",
+	}, []string{"result"})
+
+	LastRegistration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gn",
+		Name:      "hub_last_registration_timestamp_seconds",
+		Help:      "Unix time of the last successful registration with the hub.",
+	}, []string{"service"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		Calls, CallDuration, InFlight, Panics, Heartbeats, LastRegistration,
+	)
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// HeartbeatResult counts one heartbeat batch.
+func HeartbeatResult(ok bool) {
+	if ok {
+		Heartbeats.WithLabelValues("success").Inc()
+		return
+	}
+	Heartbeats.WithLabelValues("failure").Inc()
+}
+
+// Registered records a successful registration of service.
+func Registered(service string) {
+	LastRegistration.WithLabelValues(service).Set(float64(time.Now().UnixNano()) / 1e9)
+}
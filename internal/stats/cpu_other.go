@@ -0,0 +1,10 @@
+//go:build !unix
+
+package stats
+
+import "time"
+
+// cpuTime is not available on this platform; CPU usage is reported as 0.
+func cpuTime() (time.Duration, bool) {
+	return 0, false
+}
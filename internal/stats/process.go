@@ -0,0 +1,41 @@
+package stats
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+var (
+	processMu    sync.Mutex
+	lastCPUTime  time.Duration
+	lastSampled  time.Time
+	processStart = time.Now()
+)
+
+// Process returns the CPU used by the process since the previous call, as a percentage of
+// all cores, and the memory obtained from the OS by the Go runtime. The first call reports
+// the average since start.
+func Process() (cpuPercent float64, memBytes uint64) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	cpu, ok := cpuTime()
+	if !ok {
+		return 0, ms.Sys
+	}
+
+	processMu.Lock()
+	defer processMu.Unlock()
+	now := time.Now()
+	since, used := now.Sub(processStart), cpu
+	if !lastSampled.IsZero() {
+		since, used = now.Sub(lastSampled), cpu-lastCPUTime
+	}
+	lastCPUTime, lastSampled = cpu, now
+
+	if since > 0 {
+		cpuPercent = float64(used) / float64(since) / float64(runtime.NumCPU()) * 100
+	}
+	return cpuPercent, ms.Sys
+}
@@ -0,0 +1,178 @@
+// Package stats keeps the recent load of the node's services in memory so the
+// registration side can report it to the hub without depending on the transport.
+package stats
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	bucketWidth = 10 * time.Second
+	bucketCount = 6 // the window covers the last minute
+)
+
+// latencyBounds are the upper bounds of the latency buckets used to estimate percentiles.
+var latencyBounds = []time.Duration{
+	time.Millisecond, 2 * time.Millisecond, 5 * time.Millisecond, 10 * time.Millisecond,
+	25 * time.Millisecond, 50 * time.Millisecond, 100 * time.Millisecond, 250 * time.Millisecond,
+	500 * time.Millisecond, time.Second, 2500 * time.Millisecond, 5 * time.Second,
+	10 * time.Second, 30 * time.Second,
+}
+
+// Load is the recent load of one service.
+type Load struct {
+	InFlight  int64         // calls being served now
+	Requests  uint64        // calls finished within the window
+	ErrorRate float64       // share of those answered with a non-200 status
+	P99       time.Duration // upper bound of the latency bucket holding the 99th percentile
+}
+
+type bucket struct {
+	index    int64 // unix time / bucketWidth this bucket currently counts
+	requests uint64
+	errors   uint64
+	latency  []uint64 // len(latencyBounds)+1, the last one is overflow
+}
+
+type serviceStats struct {
+	inFlight atomic.Int64
+	mu       sync.Mutex
+	buckets  [bucketCount]bucket
+}
+
+var (
+	services         sync.Map // service name -> *serviceStats
+	draining         atomic.Bool
+	shedAt           atomic.Int64 // unix nanos of the latest call shed by the adaptive limiter
+	shedCount        atomic.Uint64
+	shedReported     atomic.Uint64 // shedCount as of the last heartbeat the hub accepted
+	concurrencyLimit atomic.Int64
+)
+
+func statsFor(service string) *serviceStats {
+	if v, ok := services.Load(service); ok {
+		return v.(*serviceStats)
+	}
+	v, _ := services.LoadOrStore(service, &serviceStats{})
+	return v.(*serviceStats)
+}
+
+// Enter counts a call of service as started.
+func Enter(service string) {
+	statsFor(service).inFlight.Add(1)
+}
+
+// Leave counts a call of service as finished after d; failed marks a non-200 answer.
+func Leave(service string, d time.Duration, failed bool) {
+	s := statsFor(service)
+	s.inFlight.Add(-1)
+
+	idx := time.Now().UnixNano() / int64(bucketWidth)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := &s.buckets[idx%bucketCount]
+	if b.index != idx || b.latency == nil {
+		*b = bucket{index: idx, latency: make([]uint64, len(latencyBounds)+1)}
+	}
+	b.requests++
+	if failed {
+		b.errors++
+	}
+	b.latency[sort.Search(len(latencyBounds), func(i int) bool { return d <= latencyBounds[i] })]++
+}
+
+// ServiceLoad returns the load of service over the last minute.
+func ServiceLoad(service string) Load {
+	s := statsFor(service)
+	load := Load{InFlight: s.inFlight.Load()}
+
+	oldest := time.Now().UnixNano()/int64(bucketWidth) - bucketCount + 1
+	latency := make([]uint64, len(latencyBounds)+1)
+	var errors uint64
+
+	s.mu.Lock()
+	for _, b := range s.buckets {
+		if b.latency == nil || b.index < oldest {
+			continue
+		}
+		load.Requests += b.requests
+		errors += b.errors
+		for i, n := range b.latency {
+			latency[i] += n
+		}
+	}
+	s.mu.Unlock()
+
+	if load.Requests == 0 {
+		return load
+	}
+	load.ErrorRate = float64(errors) / float64(load.Requests)
+
+	rank := uint64(float64(load.Requests)*0.99 + 0.5)
+	var seen uint64
+	for i, n := range latency {
+		seen += n
+		if seen >= rank {
+			if i < len(latencyBounds) {
+				load.P99 = latencyBounds[i]
+			} else {
+				load.P99 = latencyBounds[len(latencyBounds)-1]
+			}
+			break
+		}
+	}
+	return load
+}
+
+// SetDraining records whether the inbound server turns new calls away.
+func SetDraining(v bool) {
+	draining.Store(v)
+}
+
+// Draining reports the value last given to SetDraining.
+func Draining() bool {
+	return draining.Load()
+}
+
+// Shed records a call turned away by the adaptive concurrency limiter.
+func Shed() {
+	shedAt.Store(time.Now().UnixNano())
+	shedCount.Add(1)
+}
+
+// ClearShed forgets past shed calls, for a limiter that starts learning again.
+func ClearShed() {
+	shedAt.Store(0)
+	shedReported.Store(shedCount.Load())
+}
+
+// Shedding reports whether a call was shed within the last bucket width.
+func Shedding() bool {
+	at := shedAt.Load()
+	return at != 0 && time.Since(time.Unix(0, at)) < bucketWidth
+}
+
+// ShedSinceReport reports whether a call was shed since the last MarkShedReported, however
+// long ago, and returns the mark to pass once the hub has been told.
+func ShedSinceReport() (bool, uint64) {
+	n := shedCount.Load()
+	return n != shedReported.Load(), n
+}
+
+// MarkShedReported records that the hub has seen the shed calls up to mark.
+func MarkShedReported(mark uint64) {
+	shedReported.Store(mark)
+}
+
+// SetConcurrencyLimit records the node-wide limit the adaptive limiter has learned; 0 means none.
+func SetConcurrencyLimit(n int64) {
+	concurrencyLimit.Store(n)
+}
+
+// ConcurrencyLimit reports the value last given to SetConcurrencyLimit.
+func ConcurrencyLimit() int64 {
+	return concurrencyLimit.Load()
+}
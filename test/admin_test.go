@@ -0,0 +1,94 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jom-io/gorig-node/client/inbound/gnhttp"
+	"github.com/jom-io/gorig-node/client/register"
+	"github.com/jom-io/gorig-node/gncfg"
+)
+
+// The /_gn endpoints report what the node registered and how the hub answered.
+func TestAdminEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	svc := fmt.Sprintf("AdminSvc_%d", time.Now().UnixNano())
+
+	if err := register.Server(svc).
+		Version("v1.2.0").
+		RegName("Ping", func(ctx context.Context) (string, error) { return "pong", nil }).
+		RegName("Echo", func(ctx context.Context, s string) (string, error) { return s, nil }).
+		Create(); err != nil {
+		t.Fatalf("register %s failed: %v", svc, err)
+	}
+
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer func() {
+		register.Stop()
+		_ = register.DeregisterAll(context.Background())
+		hub.Close()
+	}()
+	gncfg.UseConfig(gncfg.GlobalConfig{HubAddr: hub.URL, NodeAddr: "127.0.0.1" + gncfg.DefNodePort})
+	if err := register.Start(); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+
+	engine := gnhttp.NewEngine()
+	get := func(path string, out interface{}) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Encoding", "identity")
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		if out != nil && w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+				t.Fatalf("decode %s failed: %v, body=%s", path, err, w.Body.String())
+			}
+		}
+		return w.Code
+	}
+
+	var st register.ServiceStatus
+	deadline := time.Now().Add(2 * time.Second)
+	for st.LastHeartbeat.IsZero() {
+		if time.Now().After(deadline) {
+			t.Fatalf("no heartbeat recorded for %s: %+v", svc, st)
+		}
+		var statuses []register.ServiceStatus
+		get("/_gn/services", &statuses)
+		for _, s := range statuses {
+			if s.Service == svc {
+				st = s
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !st.Created || !st.Registered || st.Version != "v1.2.0" || st.Methods != 2 || st.Host == "" ||
+		st.LastRegistered.IsZero() || st.LastHeartbeatError != "" {
+		t.Fatalf("unexpected status: %+v", st)
+	}
+
+	var apis []register.ApiInfo
+	if code := get("/_gn/apis/"+svc, &apis); code != http.StatusOK || len(apis) != 2 || apis[0].Method != "Ping" {
+		t.Fatalf("unexpected apis: %d %+v", code, apis)
+	}
+	if code := get("/_gn/apis/NoSuchSvc", nil); code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown service, got %d", code)
+	}
+
+	var health struct {
+		Status     string `json:"status"`
+		Registered int    `json:"registered"`
+	}
+	if code := get("/_gn/health", &health); code != http.StatusOK || health.Status != "ok" || health.Registered == 0 {
+		t.Fatalf("unexpected health: %d %+v", code, health)
+	}
+}
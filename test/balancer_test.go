@@ -0,0 +1,115 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/jom-io/gorig-node/client/outbound"
+	"github.com/jom-io/gorig-node/client/register"
+)
+
+// Verify every balancer strategy spreads picks the way it promises.
+func TestBalancers(t *testing.T) {
+	ctx := context.Background()
+	instances := []register.Instance{
+		{Host: "10.0.0.1:5807", Weight: 1},
+		{Host: "10.0.0.2:5807", Weight: 2},
+		{Host: "10.0.0.3:5807", Weight: 3},
+	}
+
+	pickN := func(b outbound.Balancer, ctx context.Context, n int) map[string]int {
+		counts := map[string]int{}
+		for i := 0; i < n; i++ {
+			ins, done := b.Pick(ctx, instances)
+			counts[ins.Host]++
+			done()
+		}
+		return counts
+	}
+
+	// Round robin: equal share.
+	for host, n := range pickN(outbound.RoundRobin(), ctx, 30) {
+		if n != 10 {
+			t.Fatalf("round robin uneven for %s: %d", host, n)
+		}
+	}
+
+	// Random: every instance eventually chosen.
+	if counts := pickN(outbound.Random(), ctx, 300); len(counts) != len(instances) {
+		t.Fatalf("random never picked some instances: %v", counts)
+	}
+
+	// Weighted: share proportional to weight.
+	counts := pickN(outbound.Weighted(), ctx, 60)
+	for _, ins := range instances {
+		if counts[ins.Host] != ins.Weight*10 {
+			t.Fatalf("weighted share mismatch for %s: %d", ins.Host, counts[ins.Host])
+		}
+	}
+
+	// Least in-flight: a busy instance is avoided until it finishes.
+	lif := outbound.LeastInFlight()
+	busy, busyDone := lif.Pick(ctx, instances)
+	for i := 0; i < len(instances)-1; i++ {
+		ins, done := lif.Pick(ctx, instances)
+		if ins.Host == busy.Host {
+			t.Fatalf("least in-flight picked busy instance %s", busy.Host)
+		}
+		defer done()
+	}
+	busyDone()
+	if ins, done := lif.Pick(ctx, instances); ins.Host != busy.Host {
+		t.Fatalf("least in-flight should return to idle instance %s, got %s", busy.Host, ins.Host)
+	} else {
+		done()
+	}
+
+	// Consistent hash: same key, same instance; removing another instance keeps the mapping.
+	ch := outbound.ConsistentHash()
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("user-%d", i)
+		kctx := outbound.WithHashKey(ctx, key)
+		first, _ := ch.Pick(kctx, instances)
+		again, _ := ch.Pick(kctx, instances)
+		if first.Host != again.Host {
+			t.Fatalf("consistent hash unstable for %s: %s vs %s", key, first.Host, again.Host)
+		}
+
+		var rest []register.Instance
+		removed := false
+		for _, ins := range instances {
+			if !removed && ins.Host != first.Host {
+				removed = true
+				continue
+			}
+			rest = append(rest, ins)
+		}
+		if moved, _ := ch.Pick(kctx, rest); moved.Host != first.Host {
+			t.Fatalf("consistent hash moved key %s from %s to %s", key, first.Host, moved.Host)
+		}
+	}
+
+	// Consistent hash on retry: with the full set attached, excluding one instance moves only its keys.
+	rctx := outbound.WithResolved(ctx, instances)
+	failed := instances[0]
+	rest := instances[1:]
+	movedKeys := 0
+	for i := 0; i < 200; i++ {
+		kctx := outbound.WithHashKey(rctx, fmt.Sprintf("order-%d", i))
+		first, _ := ch.Pick(kctx, instances)
+		retry, _ := ch.Pick(kctx, rest)
+		if retry.Host == failed.Host {
+			t.Fatalf("consistent hash picked excluded instance %s", failed.Host)
+		}
+		if first.Host != failed.Host && retry.Host != first.Host {
+			t.Fatalf("retry remapped order-%d from %s to %s", i, first.Host, retry.Host)
+		}
+		if first.Host == failed.Host {
+			movedKeys++
+		}
+	}
+	if movedKeys == 0 {
+		t.Fatalf("no key mapped to %s; the retry check covered nothing", failed.Host)
+	}
+}
@@ -0,0 +1,162 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jom-io/gorig-node/client/inbound/gnhttp"
+	"github.com/jom-io/gorig-node/client/outbound"
+	"github.com/jom-io/gorig-node/client/register"
+	"github.com/jom-io/gorig-node/gncfg"
+)
+
+// signJWT builds an HS256 token for claims.
+func signJWT(secret []byte, claims map[string]interface{}) string {
+	enc := base64.RawURLEncoding
+	payload, _ := json.Marshal(claims)
+	unsigned := enc.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." + enc.EncodeToString(payload)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + enc.EncodeToString(mac.Sum(nil))
+}
+
+// Allow lists are enforced per method, before arguments are decoded, for any authenticator.
+func TestCallerAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	svc := fmt.Sprintf("AuthSvc_%d", time.Now().UnixNano())
+	secret := []byte("jwt-secret")
+	// authenticators cannot be removed, so the keys stay the same when the test runs again
+	const keyOrder, keyAdmin = "order-key", "admin-key"
+
+	if err := register.Server(svc).
+		Allow("admin-svc"). // default for methods without their own list
+		RegName("Stats", func(ctx context.Context) (int, error) { return 42, nil }).
+		RegName("Orders", func(ctx context.Context, n int) (int, error) { return n, nil }).
+		RegName("Whoami", func(ctx context.Context) (string, error) {
+			caller, _ := register.CallerFrom(ctx)
+			return caller.ID + "/" + caller.Via, nil
+		}).
+		MethodAllow("Orders", "order-svc", "admin-svc").
+		MethodAllow("Whoami", register.AllowAny).
+		Create(); err != nil {
+		t.Fatalf("register %s failed: %v", svc, err)
+	}
+	register.UseAuthenticator(
+		register.APIKeyAuth(map[string]string{keyOrder: "order-svc", keyAdmin: "admin-svc"}),
+		register.JWTAuth(secret),
+	)
+
+	// a method list needs a registered method
+	if err := register.Server(svc).MethodAllow("Missing", "x").Create(); err == nil {
+		t.Fatalf("MethodAllow on an unregistered method should fail Create")
+	}
+	// a second service list would silently replace the first for every method
+	if err := register.Server(svc).Allow("order-svc").Create(); err == nil {
+		t.Fatalf("a second Allow on %s should fail Create", svc)
+	}
+
+	engine := gnhttp.NewEngine()
+	call := func(method, body string, header ...string) (int, register.WrappedResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/"+svc+"/"+method, bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept-Encoding", "identity")
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		var resp register.WrappedResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	if code, resp := call("Whoami", `{}`); code != http.StatusUnauthorized || resp.ErrorInfo == nil || resp.ErrorInfo.Code != register.CodeUnauthenticated {
+		t.Fatalf("anonymous call should be unauthenticated: %d %+v", code, resp.ErrorInfo)
+	}
+	if code, _ := call("Whoami", `{}`, register.HeaderAPIKey, "nope"); code != http.StatusUnauthorized {
+		t.Fatalf("unknown key should be rejected, got %d", code)
+	}
+
+	// a denied caller gets the permission error even for a body that would not decode
+	code, resp := call("Stats", `not json`, register.HeaderAPIKey, keyOrder)
+	if code != http.StatusForbidden || resp.ErrorInfo == nil || resp.ErrorInfo.Code != register.CodePermissionDenied || resp.ErrorInfo.Details["caller"] != "order-svc" {
+		t.Fatalf("order-svc must not call Stats: %d %+v", code, resp.ErrorInfo)
+	}
+	if code, resp := call("Orders", `{"args":{"arg0":7}}`, register.HeaderAPIKey, keyOrder); code != http.StatusOK || string(resp.Resp["resp0"]) != "7" {
+		t.Fatalf("order-svc may call Orders: %d %s %s", code, resp.Resp["resp0"], resp.Error)
+	}
+	if code, _ := call("Stats", `{}`, register.HeaderAPIKey, keyAdmin); code != http.StatusOK {
+		t.Fatalf("admin-svc may call Stats through the service default, got %d", code)
+	}
+
+	token := signJWT(secret, map[string]interface{}{"sub": "report-svc", "exp": time.Now().Add(time.Minute).Unix()})
+	if code, resp := call("Whoami", `{}`, "Authorization", "Bearer "+token); code != http.StatusOK || string(resp.Resp["resp0"]) != `"report-svc/jwt"` {
+		t.Fatalf("jwt caller should reach Whoami: %d %s %s", code, resp.Resp["resp0"], resp.Error)
+	}
+	expired := signJWT(secret, map[string]interface{}{"sub": "report-svc", "exp": time.Now().Add(-time.Minute).Unix()})
+	if code, _ := call("Whoami", `{}`, "Authorization", "Bearer "+expired); code != http.StatusUnauthorized {
+		t.Fatalf("expired jwt should be rejected, got %d", code)
+	}
+	forged := signJWT([]byte("other"), map[string]interface{}{"sub": "admin-svc"})
+	if code, _ := call("Stats", `{}`, "Authorization", "Bearer "+forged); code != http.StatusUnauthorized {
+		t.Fatalf("forged jwt should be rejected, got %d", code)
+	}
+
+	// the admin endpoints take the same credentials once restricted
+	admin := func(header ...string) int {
+		req := httptest.NewRequest(http.MethodGet, "/_gn/services", nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w.Code
+	}
+	register.UseAdminAllow("admin-svc")
+	defer register.UseAdminAllow()
+	if code := admin(); code != http.StatusUnauthorized {
+		t.Fatalf("anonymous admin request should be unauthenticated, got %d", code)
+	}
+	if code := admin(register.HeaderAPIKey, keyOrder); code != http.StatusForbidden {
+		t.Fatalf("order-svc must not read the admin endpoints, got %d", code)
+	}
+	if code := admin(register.HeaderAPIKey, keyAdmin); code != http.StatusOK {
+		t.Fatalf("admin-svc may read the admin endpoints, got %d", code)
+	}
+
+	// outbound callers send their credentials and see the structured error
+	node := httptest.NewServer(engine)
+	defer node.Close()
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"service":   svc,
+			"instances": []register.Instance{{Service: svc, Host: node.URL}},
+		})
+	}))
+	defer func() {
+		register.StopDiscovery()
+		hub.Close()
+	}()
+	gncfg.UseConfig(gncfg.GlobalConfig{HubAddr: hub.URL})
+
+	var stats func(ctx context.Context) (int, error)
+	if err := outbound.Service(svc).Header(register.HeaderAPIKey, keyOrder).Bind("Stats", &stats); err != nil {
+		t.Fatalf("bind failed: %v", err)
+	}
+	var remote *register.Error
+	if _, err := stats(context.Background()); !errors.As(err, &remote) || remote.Code != register.CodePermissionDenied {
+		t.Fatalf("expected a permission error, got %v", err)
+	}
+}
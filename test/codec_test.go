@@ -0,0 +1,105 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jom-io/gorig-node/client/inbound/gnhttp"
+	"github.com/jom-io/gorig-node/client/register"
+)
+
+// Every built-in codec round-trips arguments, results and structured errors through the dispatcher.
+func TestCodecNegotiation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	svc := fmt.Sprintf("CodecSvc_%d", time.Now().UnixNano())
+
+	type item struct {
+		Name  string            `json:"name"`
+		Tags  []string          `json:"tags"`
+		Attrs map[string]string `json:"attrs"`
+	}
+
+	if err := register.Server(svc).
+		RegName("Echo", func(ctx context.Context, in item, n int) (item, int, error) {
+			if n < 0 {
+				return item{}, 0, register.NewError("negative", "n must not be negative")
+			}
+			return in, n * 2, nil
+		}).
+		Create(); err != nil {
+		t.Fatalf("register %s failed: %v", svc, err)
+	}
+
+	meta := register.RegisteredServers()[svc].MethodMeta["Echo"]
+	engine := gnhttp.NewEngine()
+	in := item{Name: "gn", Tags: []string{"a", "b"}, Attrs: map[string]string{"k": "v"}}
+
+	post := func(reqCodec, respCodec register.Codec, n int) *httptest.ResponseRecorder {
+		body, err := register.PackRequestWith(reqCodec, meta, []reflect.Value{reflect.ValueOf(in), reflect.ValueOf(n)})
+		if err != nil {
+			t.Fatalf("%s pack failed: %v", reqCodec.Name(), err)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/"+svc+"/Echo", bytes.NewReader(body))
+		req.Header.Set("Content-Type", reqCodec.ContentType())
+		req.Header.Set("Accept", respCodec.ContentType())
+		req.Header.Set("Accept-Encoding", "identity")
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	codecs := []register.Codec{register.JSONCodec, register.MsgpackCodec, register.CBORCodec}
+	for _, reqCodec := range codecs {
+		for _, respCodec := range codecs {
+			w := post(reqCodec, respCodec, 21)
+			if w.Code != http.StatusOK {
+				t.Fatalf("%s->%s: unexpected status %d", reqCodec.Name(), respCodec.Name(), w.Code)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != respCodec.ContentType() {
+				t.Fatalf("%s->%s: unexpected content type %q", reqCodec.Name(), respCodec.Name(), ct)
+			}
+			outs, err := register.UnpackResponseWith(respCodec, meta, w.Body.Bytes())
+			if err != nil {
+				t.Fatalf("%s->%s: unpack failed: %v", reqCodec.Name(), respCodec.Name(), err)
+			}
+			if got := outs[0].Interface().(item); !reflect.DeepEqual(got, in) {
+				t.Fatalf("%s->%s: got %+v, want %+v", reqCodec.Name(), respCodec.Name(), got, in)
+			}
+			if got := outs[1].Interface().(int); got != 42 {
+				t.Fatalf("%s->%s: got %d, want 42", reqCodec.Name(), respCodec.Name(), got)
+			}
+		}
+
+		w := post(reqCodec, reqCodec, -1)
+		outs, err := register.UnpackResponseWith(reqCodec, meta, w.Body.Bytes())
+		if err != nil {
+			t.Fatalf("%s: unpack error response failed: %v", reqCodec.Name(), err)
+		}
+		var remote *register.Error
+		if callErr, _ := outs[2].Interface().(error); !errors.As(callErr, &remote) || remote.Code != "negative" {
+			t.Fatalf("%s: expected structured error, got %v", reqCodec.Name(), callErr)
+		}
+	}
+
+	// errors raised before the handler are answered in the negotiated codec too
+	req := httptest.NewRequest(http.MethodPost, "/"+svc+"/Echo", bytes.NewReader([]byte{0xc1}))
+	req.Header.Set("Content-Type", register.MsgpackCodec.ContentType())
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest || w.Header().Get("Content-Type") != register.MsgpackCodec.ContentType() {
+		t.Fatalf("unexpected bad request answer: %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	var envelope register.WrappedResponse
+	if err := register.MsgpackCodec.Unmarshal(w.Body.Bytes(), &envelope); err != nil || envelope.ErrorInfo == nil || envelope.ErrorInfo.Code != register.CodeBadRequest {
+		t.Fatalf("unexpected bad request envelope: %+v, err=%v", envelope, err)
+	}
+}
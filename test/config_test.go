@@ -0,0 +1,79 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jom-io/gorig-node/client/register"
+	"github.com/jom-io/gorig-node/gncfg"
+)
+
+// Hub timing comes from gncfg and invalid combinations are refused by Start.
+func TestHubConfig(t *testing.T) {
+	defaults := gncfg.GlobalConfig{}.WithDefaults()
+	if defaults.HeartbeatInterval != gncfg.DefHeartbeatInterval || defaults.HubTimeout != gncfg.DefHubTimeout ||
+		defaults.RetryMin != gncfg.DefRetryMin || defaults.RetryMax != gncfg.DefRetryMax {
+		t.Fatalf("unexpected defaults: %+v", defaults)
+	}
+
+	for _, tc := range []struct {
+		cfg  gncfg.GlobalConfig
+		want string
+	}{
+		{gncfg.GlobalConfig{HeartbeatInterval: time.Second, HeartbeatJitter: time.Second}, "heartbeat jitter"},
+		{gncfg.GlobalConfig{HeartbeatInterval: -time.Second}, "heartbeat interval"},
+		{gncfg.GlobalConfig{HubTimeout: -time.Second}, "hub timeout"},
+		{gncfg.GlobalConfig{RetryMin: time.Minute, RetryMax: time.Second}, "retry delays"},
+		{gncfg.GlobalConfig{RetryLimit: -1}, "retry limit"},
+	} {
+		if err := tc.cfg.Validate(); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%+v: expected error about %q, got %v", tc.cfg, tc.want, err)
+		}
+	}
+
+	var beats atomic.Int32
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/heartbeat" {
+			beats.Add(1)
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer func() {
+		register.Stop()
+		_ = register.DeregisterAll(context.Background())
+		hub.Close()
+	}()
+
+	svc := fmt.Sprintf("ConfigSvc_%d", time.Now().UnixNano())
+	if err := register.Server(svc).RegName("Noop", func(ctx context.Context) error { return nil }).Create(); err != nil {
+		t.Fatalf("register %s failed: %v", svc, err)
+	}
+
+	gncfg.UseConfig(gncfg.GlobalConfig{HubAddr: hub.URL, NodeAddr: "127.0.0.1" + gncfg.DefNodePort, HeartbeatJitter: 2 * time.Minute})
+	if err := register.Start(); err == nil || !strings.Contains(err.Error(), "invalid gn config") {
+		t.Fatalf("Start should refuse an invalid config, got %v", err)
+	}
+
+	gncfg.UseConfig(gncfg.GlobalConfig{
+		HubAddr:           hub.URL,
+		NodeAddr:          "127.0.0.1" + gncfg.DefNodePort,
+		HeartbeatInterval: 20 * time.Millisecond,
+		HeartbeatJitter:   10 * time.Millisecond,
+	})
+	if err := register.Start(); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for beats.Load() < 4 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected frequent heartbeats, got %d", beats.Load())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
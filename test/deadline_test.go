@@ -0,0 +1,129 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jom-io/gorig-node/client/inbound/gnhttp"
+	"github.com/jom-io/gorig-node/client/outbound"
+	"github.com/jom-io/gorig-node/client/register"
+	"github.com/jom-io/gorig-node/gncfg"
+)
+
+// The handler context carries the caller's deadline or the method timeout, and outbound
+// calls pass the remaining budget on.
+func TestDeadlinePropagation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	svc := fmt.Sprintf("DeadlineSvc_%d", time.Now().UnixNano())
+
+	// Budget reports the handler's remaining budget in ms, or -1 without a deadline.
+	budget := func(ctx context.Context) (int64, error) {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			return -1, nil
+		}
+		return time.Until(deadline).Milliseconds(), nil
+	}
+	if err := register.Server(svc).
+		RegName("Budget", budget).
+		RegName("Short", budget).
+		RegName("Wait", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}).
+		MethodTimeout("Short", 200*time.Millisecond).
+		Create(); err != nil {
+		t.Fatalf("register %s failed: %v", svc, err)
+	}
+
+	engine := gnhttp.NewEngine()
+	call := func(method string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/"+svc+"/"+method, nil)
+		req.Header.Set("Accept-Encoding", "identity")
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+	remaining := func(w *httptest.ResponseRecorder) int64 {
+		var resp struct {
+			Resp map[string]int64 `json:"resp"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode failed: %v, body=%s", err, w.Body.String())
+		}
+		return resp.Resp["resp0"]
+	}
+
+	if got := remaining(call("Budget", nil)); got != -1 {
+		t.Fatalf("expected no deadline, got %dms", got)
+	}
+	if got := remaining(call("Budget", http.Header{register.HeaderTimeout: {"5000"}})); got <= 4000 || got > 5000 {
+		t.Fatalf("expected ~5000ms from %s, got %d", register.HeaderTimeout, got)
+	}
+	abs := strconv.FormatInt(time.Now().Add(3*time.Second).UnixMilli(), 10)
+	if got := remaining(call("Budget", http.Header{register.HeaderDeadline: {abs}})); got <= 2000 || got > 3000 {
+		t.Fatalf("expected ~3000ms from %s, got %d", register.HeaderDeadline, got)
+	}
+	if got := remaining(call("Short", http.Header{register.HeaderTimeout: {"5000"}})); got <= 0 || got > 200 {
+		t.Fatalf("method timeout should cap the caller budget, got %dms", got)
+	}
+
+	w := call("Wait", http.Header{register.HeaderTimeout: {"50"}})
+	if w.Code != http.StatusOK || !json.Valid(w.Body.Bytes()) {
+		t.Fatalf("unexpected answer: %d %s", w.Code, w.Body.String())
+	}
+	var waited register.WrappedResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &waited)
+	if waited.Error != context.DeadlineExceeded.Error() {
+		t.Fatalf("handler should see its context expire, got %q", waited.Error)
+	}
+
+	if w := call("Budget", http.Header{register.HeaderTimeout: {"0"}}); w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("expected 504 for spent budget, got %d", w.Code)
+	}
+	if w := call("Budget", http.Header{register.HeaderTimeout: {"soon"}}); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for malformed header, got %d", w.Code)
+	}
+	if w := call("Budget", http.Header{register.HeaderTimeout: {"-1"}}); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a negative budget, got %d", w.Code)
+	}
+	if got := remaining(call("Budget", http.Header{register.HeaderTimeout: {"10000000000000"}})); got < time.Hour.Milliseconds() {
+		t.Fatalf("a huge budget should mean far in the future, got %dms", got)
+	}
+
+	// outbound calls send the remaining budget of their context
+	node := httptest.NewServer(engine)
+	defer node.Close()
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"service":   svc,
+			"instances": []register.Instance{{Service: svc, Host: node.URL}},
+		})
+	}))
+	defer func() {
+		register.StopDiscovery()
+		hub.Close()
+	}()
+	gncfg.UseConfig(gncfg.GlobalConfig{HubAddr: hub.URL})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	var got int64
+	if err := outbound.Service(svc).Call(ctx, "Budget", nil, &got); err != nil {
+		t.Fatalf("outbound call failed: %v", err)
+	}
+	if got <= 0 || got > 2000 {
+		t.Fatalf("expected the remote budget to be within the caller's 2s, got %dms", got)
+	}
+}
@@ -0,0 +1,136 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jom-io/gorig-node/client/register"
+	"github.com/jom-io/gorig-node/gncfg"
+)
+
+// Resolve caches the hub answer, follows long-poll updates and survives hub outages.
+func TestDiscoveryResolve(t *testing.T) {
+	svc := fmt.Sprintf("DiscoverySvc_%d", time.Now().UnixNano())
+
+	var (
+		mu        sync.Mutex
+		revision  = 1
+		hosts     = []string{"10.0.0.1:5807"}
+		changed   = make(chan struct{})
+		hubDown   atomic.Bool
+		discovers atomic.Int32
+	)
+
+	answer := func(w http.ResponseWriter) {
+		mu.Lock()
+		defer mu.Unlock()
+		ins := make([]register.Instance, 0, len(hosts))
+		for _, h := range hosts {
+			ins = append(ins, register.Instance{Service: svc, Host: h})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"service":   svc,
+			"revision":  fmt.Sprint(revision),
+			"instances": ins,
+		})
+	}
+
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hubDown.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		switch r.URL.Path {
+		case "/discover":
+			discovers.Add(1)
+			answer(w)
+		case "/discover/watch":
+			var req struct {
+				Revision string `json:"revision"`
+				WaitMs   int64  `json:"wait_ms"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			mu.Lock()
+			current, ch := fmt.Sprint(revision), changed
+			mu.Unlock()
+			if req.Revision == current {
+				select {
+				case <-ch:
+				case <-time.After(time.Duration(req.WaitMs) * time.Millisecond):
+				case <-r.Context().Done():
+					return
+				}
+			}
+			answer(w)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer func() {
+		register.StopDiscovery()
+		hub.Close()
+	}()
+
+	gncfg.UseConfig(gncfg.GlobalConfig{HubAddr: hub.URL})
+
+	ins, err := register.Resolve(svc, "", "")
+	if err != nil {
+		t.Fatalf("resolve failed: %v", err)
+	}
+	if len(ins) != 1 || ins[0].Host != "10.0.0.1:5807" {
+		t.Fatalf("unexpected instances: %+v", ins)
+	}
+
+	// Cached: a second resolve must not hit /discover again.
+	if _, err := register.Resolve(svc, "", ""); err != nil {
+		t.Fatalf("cached resolve failed: %v", err)
+	}
+	if n := discovers.Load(); n != 1 {
+		t.Fatalf("expected 1 discover request, got %d", n)
+	}
+
+	// Push a change through the long-poll watch.
+	mu.Lock()
+	revision++
+	hosts = []string{"10.0.0.1:5807", "10.0.0.2:5807"}
+	close(changed)
+	changed = make(chan struct{})
+	mu.Unlock()
+
+	deadline := time.After(2 * time.Second)
+	for {
+		ins, err = register.Resolve(svc, "", "")
+		if err != nil {
+			t.Fatalf("resolve failed: %v", err)
+		}
+		if len(ins) == 2 {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("timed out waiting for watch update, instances=%+v", ins)
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+
+	// Hub outage: the last known list keeps being served.
+	hubDown.Store(true)
+	ins, err = register.Resolve(svc, "", "")
+	if err != nil {
+		t.Fatalf("resolve during hub outage failed: %v", err)
+	}
+	if len(ins) != 2 {
+		t.Fatalf("expected last known instances during outage, got %+v", ins)
+	}
+
+	// Unknown keys still need the hub.
+	if _, err := register.Resolve(svc, "other-env", ""); err == nil {
+		t.Fatalf("expected error resolving uncached service while hub is down")
+	}
+}
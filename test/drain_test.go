@@ -0,0 +1,134 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jom-io/gorig-node/client/inbound/gnhttp"
+	"github.com/jom-io/gorig-node/client/register"
+	"github.com/jom-io/gorig-node/gncfg"
+	"github.com/jom-io/gorig-node/gnnode"
+)
+
+// Shutdown lets calls in flight finish, turns new ones away and gives up once ctx ends.
+func TestGracefulShutdown(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	svc := fmt.Sprintf("DrainSvc_%d", time.Now().UnixNano())
+	entered := make(chan struct{}, 1)
+	release := make(chan struct{})
+	stuck := make(chan struct{})
+	defer close(stuck)
+	if err := register.Server(svc).
+		RegName("Slow", func(ctx context.Context) (string, error) {
+			entered <- struct{}{}
+			<-release
+			return "done", nil
+		}).
+		RegName("Stuck", func(ctx context.Context) (string, error) {
+			entered <- struct{}{}
+			<-stuck // ignores ctx on purpose
+			return "late", nil
+		}).
+		Create(); err != nil {
+		t.Fatalf("register %s failed: %v", svc, err)
+	}
+
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer hub.Close()
+
+	port := freePort(t)
+	gncfg.UseConfig(gncfg.GlobalConfig{HubAddr: hub.URL, NodeAddr: "127.0.0.1" + port})
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	call := func(method string) (int, error) {
+		resp, err := client.Post("http://127.0.0.1"+port+"/"+svc+"/"+method, "application/json", strings.NewReader(`{}`))
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+	startCall := func(method string) chan int {
+		codes := make(chan int, 1)
+		go func() {
+			code, _ := call(method)
+			codes <- code
+		}()
+		select {
+		case <-entered:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s handler never started", method)
+		}
+		return codes
+	}
+	shutdown := func(ctx context.Context) chan error {
+		errs := make(chan error, 1)
+		go func() { errs <- gnnode.Shutdown("GORIG-NODE", ctx) }()
+		deadline := time.Now().Add(2 * time.Second)
+		for !gnhttp.Draining() {
+			if time.Now().After(deadline) {
+				t.Fatalf("shutdown never started draining")
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		return errs
+	}
+
+	if err := gnhttp.Start(port); err != nil {
+		t.Skipf("inbound port unavailable: %v", err)
+	}
+	slow := startCall("Slow")
+	errs := shutdown(context.Background())
+
+	if code, err := call("Slow"); err != nil || code != http.StatusServiceUnavailable {
+		t.Fatalf("a new call while draining must get 503, got %d %v", code, err)
+	}
+	select {
+	case err := <-errs:
+		t.Fatalf("shutdown returned with a call in flight: %v", err)
+	default:
+	}
+
+	close(release)
+	if code := <-slow; code != http.StatusOK {
+		t.Fatalf("the call in flight must complete, got %d", code)
+	}
+	select {
+	case err := <-errs:
+		if err != nil {
+			t.Fatalf("shutdown failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("shutdown did not return after the last call finished")
+	}
+
+	// a handler that never finishes must not hold shutdown past ctx
+	if err := gnhttp.Start(port); err != nil {
+		t.Fatalf("restart failed: %v", err)
+	}
+	startCall("Stuck")
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	begin := time.Now()
+	select {
+	case err := <-shutdown(ctx):
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("shutdown should report the expired ctx, got %v", err)
+		}
+		if took := time.Since(begin); took > 2*time.Second {
+			t.Fatalf("shutdown took %s after ctx expired", took)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("shutdown ignored the expired ctx")
+	}
+}
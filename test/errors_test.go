@@ -0,0 +1,93 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jom-io/gorig-node/client/inbound/gnhttp"
+	"github.com/jom-io/gorig-node/client/register"
+)
+
+// Structured errors survive the round trip; plain errors keep the legacy shape.
+func TestStructuredErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	svc := fmt.Sprintf("ErrorSvc_%d", time.Now().UnixNano())
+	errOutOfStock := register.NewError("out_of_stock", "item is out of stock")
+
+	type orderReq struct {
+		ItemID int `json:"item_id"`
+	}
+
+	if err := register.Server(svc).
+		RegName("Order", func(ctx context.Context, req orderReq) (int, error) {
+			if req.ItemID == 0 {
+				return 0, errors.New("item id required")
+			}
+			return 0, fmt.Errorf("order failed: %w", errOutOfStock.WithDetail("item_id", req.ItemID).WithRetryable(true))
+		}).
+		Create(); err != nil {
+		t.Fatalf("register %s failed: %v", svc, err)
+	}
+
+	meta := register.RegisteredServers()[svc].MethodMeta["Order"]
+	engine := gnhttp.NewEngine()
+
+	call := func(req orderReq) (register.WrappedResponse, error) {
+		body, err := register.PackRequest(meta, []reflect.Value{reflect.ValueOf(req)})
+		if err != nil {
+			t.Fatalf("pack request failed: %v", err)
+		}
+		resp := performRequest(engine, http.MethodPost, "/"+svc+"/Order", body)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("unexpected status %d, body=%s", resp.Code, resp.Body.String())
+		}
+		var raw register.WrappedResponse
+		if err := json.Unmarshal(resp.Body.Bytes(), &raw); err != nil {
+			t.Fatalf("decode envelope failed: %v", err)
+		}
+		out, err := register.UnpackResponse(meta, resp.Body.Bytes())
+		if err != nil {
+			t.Fatalf("unpack response failed: %v", err)
+		}
+		callErr, _ := out[len(out)-1].Interface().(error)
+		return raw, callErr
+	}
+
+	raw, err := call(orderReq{ItemID: 7})
+	if raw.Error != "order failed: [out_of_stock] item is out of stock" {
+		t.Fatalf("legacy error field mismatch: %q", raw.Error)
+	}
+	var remote *register.Error
+	if !errors.As(err, &remote) {
+		t.Fatalf("expected *register.Error, got %T: %v", err, err)
+	}
+	if remote.Code != "out_of_stock" || !remote.Retryable || remote.TraceID != "test-request-id" ||
+		remote.Message != "order failed: item is out of stock" {
+		t.Fatalf("unexpected remote error: %+v", remote)
+	}
+	if id, _ := remote.Details["item_id"].(float64); id != 7 {
+		t.Fatalf("unexpected details: %+v", remote.Details)
+	}
+	if !errors.Is(err, errOutOfStock) {
+		t.Fatalf("errors.Is should match the sentinel by code")
+	}
+	if errOutOfStock.Details != nil || errOutOfStock.TraceID != "" {
+		t.Fatalf("sentinel error must not be mutated: %+v", errOutOfStock)
+	}
+
+	raw, err = call(orderReq{})
+	if raw.ErrorInfo != nil {
+		t.Fatalf("plain error should not carry error_info: %+v", raw.ErrorInfo)
+	}
+	if err == nil || err.Error() != "item id required" || errors.As(err, &remote) {
+		t.Fatalf("expected plain error, got %T: %v", err, err)
+	}
+}
@@ -0,0 +1,134 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jom-io/gorig-node/client/inbound/gnhttp"
+	"github.com/jom-io/gorig-node/client/register"
+	"github.com/jom-io/gorig-node/gncfg"
+)
+
+// Heartbeats carry load and status; a hub rejecting them gets the legacy shape instead.
+func TestHeartbeatLoad(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	svc := fmt.Sprintf("LoadSvc_%d", time.Now().UnixNano())
+
+	if err := register.Server(svc).
+		RegName("Work", func(ctx context.Context, n int) (int, error) { return n, nil }).
+		Create(); err != nil {
+		t.Fatalf("register %s failed: %v", svc, err)
+	}
+
+	engine := gnhttp.NewEngine()
+	for _, body := range []string{`{"args":{"arg0":1}}`, `{"args":{"arg0":2}}`, `{"args":{"arg0":3}}`, `{"args":{"arg0":"x"}}`} {
+		performRequest(engine, http.MethodPost, "/"+svc+"/Work", []byte(body))
+	}
+
+	var (
+		mu         sync.Mutex
+		heartbeats []string
+		strict     atomic.Bool // reject heartbeats with fields beyond service and host
+	)
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/heartbeat" {
+			_, _ = w.Write([]byte(`{}`))
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		heartbeats = append(heartbeats, string(body))
+		mu.Unlock()
+		if strict.Load() {
+			if strings.Contains(string(body), `"load"`) {
+				http.Error(w, "unknown field load", http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte(`{"epoch":"legacy-hub"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"epoch":"load-hub"}`))
+	}))
+	defer func() {
+		register.Stop()
+		_ = register.DeregisterAll(context.Background())
+		hub.Close()
+	}()
+	gncfg.UseConfig(gncfg.GlobalConfig{HubAddr: hub.URL, NodeAddr: "127.0.0.1" + gncfg.DefNodePort})
+
+	waitHeartbeats := func(n int) []string {
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			mu.Lock()
+			got := append([]string(nil), heartbeats...)
+			mu.Unlock()
+			if len(got) >= n {
+				return got
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %d heartbeats, got %d", n, len(got))
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	if err := register.Start(); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	var batch struct {
+		Services []struct {
+			Service string `json:"service"`
+			Status  string `json:"status"`
+			Load    *struct {
+				InFlight  int64   `json:"in_flight"`
+				Requests  uint64  `json:"requests"`
+				ErrorRate float64 `json:"error_rate"`
+				P99Ms     float64 `json:"p99_ms"`
+			} `json:"load"`
+		} `json:"services"`
+		Node *struct {
+			MemBytes uint64 `json:"mem_bytes"`
+		} `json:"node"`
+	}
+	if err := json.Unmarshal([]byte(waitHeartbeats(1)[0]), &batch); err != nil {
+		t.Fatalf("decode heartbeat failed: %v", err)
+	}
+	found := false
+	for _, s := range batch.Services {
+		if s.Service != svc {
+			continue
+		}
+		found = true
+		if s.Status != "serving" || s.Load == nil || s.Load.Requests != 4 || s.Load.ErrorRate != 0.25 || s.Load.P99Ms <= 0 || s.Load.InFlight != 0 {
+			t.Fatalf("unexpected heartbeat for %s: %+v %+v", svc, s, s.Load)
+		}
+	}
+	if !found || batch.Node == nil || batch.Node.MemBytes == 0 {
+		t.Fatalf("heartbeat misses the service or node load: %s", heartbeats[0])
+	}
+
+	// restart the loop against a hub that only knows the legacy shape
+	register.Stop()
+	strict.Store(true)
+	if err := register.Start(); err != nil {
+		t.Fatalf("restart failed: %v", err)
+	}
+	got := waitHeartbeats(3)
+	if !strings.Contains(got[1], `"load"`) || strings.Contains(got[2], `"load"`) || strings.Contains(got[2], `"status"`) {
+		t.Fatalf("expected an extended heartbeat followed by a legacy one, got:\n%s\n%s", got[1], got[2])
+	}
+	if !strings.Contains(got[2], svc) {
+		t.Fatalf("legacy heartbeat lost the service: %s", got[2])
+	}
+}
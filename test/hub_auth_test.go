@@ -0,0 +1,171 @@
+package test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jom-io/gorig-node/client/register"
+	"github.com/jom-io/gorig-node/gncfg"
+)
+
+type countingTransport struct {
+	n atomic.Int32
+}
+
+func (t *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.n.Add(1)
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+// Hub requests carry the bearer token and HMAC signature, and client certificates over TLS.
+func TestHubAuth(t *testing.T) {
+	svc := fmt.Sprintf("HubAuthSvc_%d", time.Now().UnixNano())
+	if err := register.Server(svc).RegName("Noop", func(ctx context.Context) error { return nil }).Create(); err != nil {
+		t.Fatalf("register %s failed: %v", svc, err)
+	}
+	defer func() {
+		register.Stop()
+		register.UseHubClient(nil)
+		_ = register.DeregisterAll(context.Background())
+	}()
+
+	const secret, token = "s3cret", "node-token"
+	var verified atomic.Int32
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ts := r.Header.Get(register.HeaderHubTimestamp)
+		want := register.HubSignature(secret, ts, r.Method, r.URL.Path, body)
+		if r.Header.Get("Authorization") != "Bearer "+token || r.Header.Get(register.HeaderHubSignature) != want {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		verified.Add(1)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer hub.Close()
+
+	transport := &countingTransport{}
+	register.UseHubClient(&http.Client{Transport: transport})
+	gncfg.UseConfig(gncfg.GlobalConfig{HubAddr: hub.URL, NodeAddr: "127.0.0.1" + gncfg.DefNodePort, HubToken: token, HubSecret: secret})
+	if err := register.Start(); err != nil {
+		t.Fatalf("signed registration failed: %v", err)
+	}
+	if verified.Load() == 0 || transport.n.Load() == 0 {
+		t.Fatalf("expected verified requests through the custom client: verified=%d sent=%d", verified.Load(), transport.n.Load())
+	}
+	register.Stop()
+	register.UseHubClient(nil)
+
+	gncfg.UseConfig(gncfg.GlobalConfig{HubAddr: hub.URL, NodeAddr: "127.0.0.1" + gncfg.DefNodePort, HubToken: token, HubSecret: "wrong"})
+	if err := register.Start(); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("badly signed registration should be refused, got %v", err)
+	}
+	register.Stop()
+
+	// mutual TLS: the hub only accepts the node's client certificate
+	dir := t.TempDir()
+	clientCert, certFile, keyFile := writeClientCert(t, dir)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	var peer atomic.Value
+	tlsHub := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		peer.Store(r.TLS.PeerCertificates[0].Subject.CommonName)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	tlsHub.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	tlsHub.StartTLS()
+	defer tlsHub.Close()
+	caFile := filepath.Join(dir, "hub-ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsHub.Certificate().Raw}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	gncfg.UseConfig(gncfg.GlobalConfig{HubAddr: tlsHub.Listener.Addr().String(), NodeAddr: "127.0.0.1" + gncfg.DefNodePort, HubCertFile: certFile})
+	if err := register.Start(); err == nil || !strings.Contains(err.Error(), "cert and key") {
+		t.Fatalf("cert without key should be rejected, got %v", err)
+	}
+
+	gncfg.UseConfig(gncfg.GlobalConfig{
+		HubAddr:     tlsHub.Listener.Addr().String(), // no scheme: https because TLS is configured
+		NodeAddr:    "127.0.0.1" + gncfg.DefNodePort,
+		HubCertFile: certFile,
+		HubKeyFile:  keyFile,
+		HubCAFile:   caFile,
+	})
+	if err := register.Start(); err != nil {
+		t.Fatalf("mTLS registration failed: %v", err)
+	}
+	if got, _ := peer.Load().(string); got != "gn-node" {
+		t.Fatalf("hub saw client certificate %q", got)
+	}
+	register.Stop()
+
+	// callers that never run Start must not fall back to a client without the configured CA
+	gncfg.UseConfig(gncfg.GlobalConfig{
+		HubAddr:   tlsHub.Listener.Addr().String(),
+		HubCAFile: filepath.Join(dir, "missing-ca.pem"),
+	})
+	if _, err := register.ResolveContext(context.Background(), svc, "", ""); err == nil || !strings.Contains(err.Error(), "load hub CA") {
+		t.Fatalf("lookup with an unreadable hub CA should fail with the load error, got %v", err)
+	}
+}
+
+// writeClientCert creates a self-signed client certificate and returns it with its PEM files.
+func writeClientCert(t *testing.T, dir string) (*x509.Certificate, string, string) {
+	return writeCert(t, dir, "node", &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "gn-node"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+}
+
+// writeCert self-signs tmpl with a fresh key and writes name.pem and name-key.pem into dir.
+func writeCert(t *testing.T, dir, name string, tmpl *x509.Certificate) (*x509.Certificate, string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.SerialNumber = big.NewInt(time.Now().UnixNano())
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+	tmpl.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign
+	tmpl.BasicConstraintsValid = true
+	tmpl.IsCA = true
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(dir, name+".pem"), filepath.Join(dir, name+"-key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return cert, certFile, keyFile
+}
@@ -0,0 +1,189 @@
+package test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jom-io/gorig-node/client/inbound/gnhttp"
+	"github.com/jom-io/gorig-node/client/outbound"
+	"github.com/jom-io/gorig-node/client/register"
+	"github.com/jom-io/gorig-node/gncfg"
+)
+
+// The inbound server speaks mutual TLS, advertises https to the hub and reloads rotated certificates.
+func TestInboundTLS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	svc := fmt.Sprintf("TLSSvc_%d", time.Now().UnixNano())
+	if err := register.Server(svc).
+		RegName("Echo", func(ctx context.Context, s string) (string, error) { return s, nil }).
+		Create(); err != nil {
+		t.Fatalf("register %s failed: %v", svc, err)
+	}
+
+	dir := t.TempDir()
+	serverTmpl := func(cn string) *x509.Certificate {
+		return &x509.Certificate{
+			Subject:     pkix.Name{CommonName: cn},
+			IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		}
+	}
+	serverCert, certFile, keyFile := writeCert(t, dir, "server", serverTmpl("gn-server"))
+	_, clientCertFile, clientKeyFile := writeClientCert(t, dir)
+	clientPair, err := tls.LoadX509KeyPair(clientCertFile, clientKeyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	host := "127.0.0.1" + gncfg.DefNodePort
+	var scheme atomic.Value
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/register":
+			var req struct {
+				Service string `json:"service"`
+				Scheme  string `json:"scheme"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			if req.Service == svc {
+				scheme.Store(req.Scheme)
+			}
+			_, _ = w.Write([]byte(`{}`))
+		case "/discover":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"service":   svc,
+				"instances": []register.Instance{{Service: svc, Host: host, Scheme: "https"}},
+			})
+		default:
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	defer func() {
+		register.Stop()
+		register.StopDiscovery()
+		_ = register.DeregisterAll(context.Background())
+		hub.Close()
+	}()
+
+	gncfg.UseConfig(gncfg.GlobalConfig{
+		HubAddr:         hub.URL,
+		NodeAddr:        host,
+		TLSCertFile:     certFile,
+		TLSKeyFile:      keyFile,
+		TLSClientCAFile: clientCertFile,
+		TLSMinVersion:   "1.3",
+	})
+	if err := register.Start(); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	if got, _ := scheme.Load().(string); got != "https" {
+		t.Fatalf("registration should advertise https, got %q", got)
+	}
+	if err := gnhttp.Start(gncfg.DefNodePort); err != nil {
+		t.Skipf("inbound port unavailable: %v", err)
+	}
+	defer func() { _ = gnhttp.Shutdown(context.Background()) }()
+
+	clientFor := func(root *x509.Certificate, withCert bool) *http.Client {
+		roots := x509.NewCertPool()
+		roots.AddCert(root)
+		cfg := &tls.Config{RootCAs: roots}
+		if withCert {
+			cfg.Certificates = []tls.Certificate{clientPair}
+		}
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}, Timeout: 5 * time.Second}
+	}
+
+	var echo func(ctx context.Context, s string) (string, error)
+	if err := outbound.Service(svc).HTTPClient(clientFor(serverCert, true)).Bind("Echo", &echo); err != nil {
+		t.Fatalf("bind failed: %v", err)
+	}
+	if got, err := echo(context.Background(), "over tls"); err != nil || got != "over tls" {
+		t.Fatalf("mTLS call failed: %q %v", got, err)
+	}
+
+	// probes and scrapes connect without a client certificate; API calls need one
+	for _, path := range []string{"/healthz", "/readyz", "/metrics"} {
+		resp, err := clientFor(serverCert, false).Get("https://" + host + path)
+		if err != nil {
+			t.Fatalf("%s without a client certificate failed the handshake: %v", path, err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	callEcho := func(client *http.Client) int {
+		resp, err := client.Post("https://"+host+"/"+svc+"/Echo", "application/json", strings.NewReader(`{"args":{"arg0":"hi"}}`))
+		if err != nil {
+			t.Fatalf("echo call failed: %v", err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := callEcho(clientFor(serverCert, false)); code != http.StatusUnauthorized {
+		t.Fatalf("an API call without a client certificate must be refused, got %d", code)
+	}
+	listServices := func(client *http.Client) int {
+		resp, err := client.Get("https://" + host + "/_gn/services")
+		if err != nil {
+			t.Fatalf("admin request failed: %v", err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := listServices(clientFor(serverCert, false)); code != http.StatusUnauthorized {
+		t.Fatalf("the admin endpoints without a client certificate must be refused, got %d", code)
+	}
+	if code := listServices(clientFor(serverCert, true)); code != http.StatusOK {
+		t.Fatalf("the admin endpoints with a client certificate failed with %d", code)
+	}
+
+	// rotate the client CA in place; certificates of the old CA stop working on new connections
+	_, _, _ = writeCert(t, dir, "node", &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "gn-node-rotated"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	caFuture := time.Now().Add(time.Minute)
+	_ = os.Chtimes(clientCertFile, caFuture, caFuture)
+	if code := callEcho(clientFor(serverCert, true)); code != http.StatusUnauthorized {
+		t.Fatalf("a certificate of the replaced client CA must be refused, got %d", code)
+	}
+	rotatedPair, err := tls.LoadX509KeyPair(clientCertFile, clientKeyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientPair = rotatedPair
+	if code := callEcho(clientFor(serverCert, true)); code != http.StatusOK {
+		t.Fatalf("a certificate of the rotated client CA must be accepted, got %d", code)
+	}
+
+	// rotate the certificate in place; new connections get it without a restart
+	rotated, _, _ := writeCert(t, dir, "server", serverTmpl("gn-server-rotated"))
+	future := time.Now().Add(time.Minute)
+	_ = os.Chtimes(certFile, future, future)
+	resp, err := clientFor(rotated, true).Get("https://" + host + "/healthz")
+	if err != nil {
+		t.Fatalf("request after rotation failed: %v", err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if cn := resp.TLS.PeerCertificates[0].Subject.CommonName; !strings.HasSuffix(cn, "rotated") {
+		t.Fatalf("server still presents %q after rotation", cn)
+	}
+}
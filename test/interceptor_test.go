@@ -0,0 +1,109 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jom-io/gorig-node/client/inbound/gnhttp"
+	"github.com/jom-io/gorig-node/client/register"
+)
+
+// Global and service interceptors run in order around the handler and can rewrite or stop calls.
+func TestInterceptors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	svc := fmt.Sprintf("InterceptSvc_%d", time.Now().UnixNano())
+
+	var (
+		mu    sync.Mutex
+		trace []string
+	)
+	record := func(s string) {
+		mu.Lock()
+		trace = append(trace, s)
+		mu.Unlock()
+	}
+
+	// global interceptors stay installed for the whole test binary, so only watch this service
+	register.UseInterceptor(func(ctx context.Context, inv *register.Invocation, next register.Invoker) ([]interface{}, error) {
+		if inv.Service != svc {
+			return next(ctx, inv)
+		}
+		record("global:" + inv.Method)
+		return next(ctx, inv)
+	})
+
+	auth := func(ctx context.Context, inv *register.Invocation, next register.Invoker) ([]interface{}, error) {
+		record("auth")
+		if name, _ := inv.Args[0].(string); name == "" {
+			return nil, register.NewError("forbidden", "name required")
+		}
+		return next(ctx, inv)
+	}
+	upper := func(ctx context.Context, inv *register.Invocation, next register.Invoker) ([]interface{}, error) {
+		record("upper")
+		inv.Args[0] = strings.ToUpper(inv.Args[0].(string))
+		res, err := next(ctx, inv)
+		if err == nil && len(res) > 0 {
+			record(fmt.Sprintf("result:%v", res[0]))
+		}
+		return res, err
+	}
+
+	if err := register.Server(svc).
+		Use(auth, upper).
+		RegName("Greet", func(ctx context.Context, name string) (string, error) {
+			record("handler")
+			return "hello " + name, nil
+		}).
+		RegName("Echo", func(ctx context.Context, name string) string {
+			return name
+		}).
+		Create(); err != nil {
+		t.Fatalf("register %s failed: %v", svc, err)
+	}
+
+	engine := gnhttp.NewEngine()
+
+	resp := performRequest(engine, http.MethodPost, "/"+svc+"/Greet", []byte(`{"args":{"arg0":"gn"}}`))
+	if resp.Body.String() != `{"resp":{"resp0":"hello GN"},"error":""}` {
+		t.Fatalf("unexpected answer: %s", resp.Body.String())
+	}
+	want := "global:Greet,auth,upper,handler,result:hello GN"
+	if got := strings.Join(trace, ","); got != want {
+		t.Fatalf("unexpected order: %s, want %s", got, want)
+	}
+
+	trace = nil
+	resp = performRequest(engine, http.MethodPost, "/"+svc+"/Greet", []byte(`{"args":{"arg0":""}}`))
+	var w register.WrappedResponse
+	if err := json.Unmarshal(resp.Body.Bytes(), &w); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if resp.Code != http.StatusOK || w.ErrorInfo == nil || w.ErrorInfo.Code != "forbidden" {
+		t.Fatalf("expected forbidden envelope, got %d %s", resp.Code, resp.Body.String())
+	}
+	if got := strings.Join(trace, ","); got != "global:Greet,auth" {
+		t.Fatalf("short-circuited call reached %s", got)
+	}
+
+	// methods without an error return still report interceptor failures
+	resp = performRequest(engine, http.MethodPost, "/"+svc+"/Echo", []byte(`{"args":{"arg0":""}}`))
+	if err := json.Unmarshal(resp.Body.Bytes(), &w); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if w.ErrorInfo == nil || w.ErrorInfo.Code != "forbidden" {
+		t.Fatalf("expected forbidden envelope for Echo, got %s", resp.Body.String())
+	}
+	resp = performRequest(engine, http.MethodPost, "/"+svc+"/Echo", []byte(`{"args":{"arg0":"x"}}`))
+	if resp.Body.String() != `{"resp":{"resp0":"X"},"error":""}` {
+		t.Fatalf("unexpected Echo answer: %s", resp.Body.String())
+	}
+}
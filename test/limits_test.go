@@ -0,0 +1,114 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jom-io/gorig-node/client/inbound/gnhttp"
+	"github.com/jom-io/gorig-node/client/register"
+)
+
+// A saturated method answers with a retryable overloaded error without starving its neighbours.
+func TestLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	svc := fmt.Sprintf("LimitSvc_%d", time.Now().UnixNano())
+	entered := make(chan struct{})
+	unblock := make(chan struct{})
+	if err := register.Server(svc).
+		RegName("Slow", func(ctx context.Context) (string, error) {
+			entered <- struct{}{}
+			<-unblock
+			return "done", nil
+		}).
+		RegName("Fast", func(ctx context.Context) (string, error) { return "ok", nil }).
+		RegName("Limited", func(ctx context.Context) (string, error) { return "ok", nil }).
+		MethodMaxConcurrency("Slow", 1).
+		MethodRateLimit("Limited", 1, 2).
+		MaxConcurrency(10).
+		Create(); err != nil {
+		t.Fatalf("register %s failed: %v", svc, err)
+	}
+	if err := register.Server(svc).MethodRateLimit("Missing", 1, 1).Create(); err == nil {
+		t.Fatalf("limits on an unknown method should fail Create")
+	}
+	for name, bad := range map[string]*register.ServerCreator{
+		"zero rate":             register.Server(svc).RateLimit(0, 1),
+		"zero burst":            register.Server(svc).RateLimit(10, 0),
+		"zero concurrency":      register.Server(svc).MaxConcurrency(0),
+		"negative method rate":  register.Server(svc).MethodRateLimit("Fast", -1, 1),
+		"zero method burst":     register.Server(svc).MethodRateLimit("Fast", 1, 0),
+		"negative method limit": register.Server(svc).MethodMaxConcurrency("Fast", -1),
+	} {
+		if err := bad.Create(); err == nil {
+			t.Fatalf("%s should fail Create", name)
+		}
+	}
+
+	engine := gnhttp.NewEngine()
+	call := func(method string) (int, string, *register.Error) {
+		w := performRequest(engine, http.MethodPost, "/"+svc+"/"+method, []byte(`{}`))
+		var resp register.WrappedResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, w.Header().Get("Retry-After"), resp.ErrorInfo
+	}
+
+	done := make(chan int)
+	go func() {
+		code, _, _ := call("Slow")
+		done <- code
+	}()
+	<-entered
+
+	code, _, e := call("Slow")
+	if code != http.StatusTooManyRequests || e == nil || e.Code != register.CodeOverloaded || !e.Retryable || e.Details["limit"] != "concurrency" {
+		t.Fatalf("second Slow call should be overloaded: %d %+v", code, e)
+	}
+	if code, _, _ := call("Fast"); code != http.StatusOK {
+		t.Fatalf("Fast must not be starved by Slow, got %d", code)
+	}
+	close(unblock)
+	if code := <-done; code != http.StatusOK {
+		t.Fatalf("first Slow call failed with %d", code)
+	}
+
+	for i := 0; i < 2; i++ {
+		if code, _, e := call("Limited"); code != http.StatusOK {
+			t.Fatalf("call %d within the burst failed: %d %+v", i, code, e)
+		}
+	}
+	code, retryAfter, e := call("Limited")
+	if code != http.StatusTooManyRequests || e == nil || e.Details["limit"] != "rate" || retryAfter != "1" {
+		t.Fatalf("third Limited call should be rate limited: %d retry-after=%q %+v", code, retryAfter, e)
+	}
+
+	// a call the service bucket refuses keeps its method token
+	shared := fmt.Sprintf("SharedLimitSvc_%d", time.Now().UnixNano())
+	if err := register.Server(shared).
+		RegName("Open", func(ctx context.Context) (string, error) { return "ok", nil }).
+		RegName("Scarce", func(ctx context.Context) (string, error) { return "ok", nil }).
+		RateLimit(20, 1).
+		MethodRateLimit("Scarce", 0.01, 1).
+		Create(); err != nil {
+		t.Fatalf("register %s failed: %v", shared, err)
+	}
+	engine = gnhttp.NewEngine()
+	sharedCall := func(method string) int {
+		return performRequest(engine, http.MethodPost, "/"+shared+"/"+method, []byte(`{}`)).Code
+	}
+	if code := sharedCall("Open"); code != http.StatusOK {
+		t.Fatalf("Open should take the service token, got %d", code)
+	}
+	if code := sharedCall("Scarce"); code != http.StatusTooManyRequests {
+		t.Fatalf("Scarce should be refused by the empty service bucket, got %d", code)
+	}
+	time.Sleep(80 * time.Millisecond) // the service bucket refills, the method bucket would not
+	if code := sharedCall("Scarce"); code != http.StatusOK {
+		t.Fatalf("Scarce lost its method token to a refused call, got %d", code)
+	}
+}
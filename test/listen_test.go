@@ -0,0 +1,77 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jom-io/gorig-node/client/inbound/gnhttp"
+	"github.com/jom-io/gorig-node/client/register"
+)
+
+// A service with its own Host port is served only there, and a taken port fails Start.
+func TestServicePortListeners(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	nodePort, svcPort := freePort(t), freePort(t)
+	svc := fmt.Sprintf("PortSvc_%d", time.Now().UnixNano())
+	if err := register.Server(svc).
+		Host("127.0.0.1"+svcPort).
+		RegName("Ping", func(ctx context.Context) (string, error) { return "pong", nil }).
+		Create(); err != nil {
+		t.Fatalf("register %s failed: %v", svc, err)
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	call := func(port string) int {
+		resp, err := client.Post("http://127.0.0.1"+port+"/"+svc+"/Ping", "application/json", strings.NewReader(`{}`))
+		if err != nil {
+			t.Fatalf("call on %s failed: %v", port, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if err := gnhttp.Start(nodePort); err != nil {
+		t.Skipf("inbound ports unavailable: %v", err)
+	}
+	if code := call(svcPort); code != http.StatusOK {
+		t.Fatalf("service port should serve %s, got %d", svc, code)
+	}
+	if code := call(nodePort); code != http.StatusNotFound {
+		t.Fatalf("node port must not serve %s, got %d", svc, code)
+	}
+	if err := gnhttp.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+
+	taken, err := net.Listen("tcp", svcPort)
+	if err != nil {
+		t.Skipf("cannot occupy %s: %v", svcPort, err)
+	}
+	defer taken.Close()
+	if err := gnhttp.Start(nodePort); err == nil {
+		_ = gnhttp.Shutdown(context.Background())
+		t.Fatalf("start must fail when the service port is taken")
+	}
+	if conn, err := net.DialTimeout("tcp", "127.0.0.1"+nodePort, time.Second); err == nil {
+		conn.Close()
+		t.Fatalf("a failed start must not leave the node port listening")
+	}
+}
+
+// freePort returns a ":port" nothing listens on right now.
+func freePort(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("no free port: %v", err)
+	}
+	defer ln.Close()
+	return fmt.Sprintf(":%d", ln.Addr().(*net.TCPAddr).Port)
+}
@@ -0,0 +1,90 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jom-io/gorig-node/client/inbound/gnhttp"
+	"github.com/jom-io/gorig-node/client/register"
+	"github.com/jom-io/gorig-node/gncfg"
+)
+
+// /metrics reports call outcomes per method plus heartbeat and registration state.
+func TestMetricsEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	svc := fmt.Sprintf("MetricsSvc_%d", time.Now().UnixNano())
+
+	if err := register.Server(svc).
+		RegName("Check", func(ctx context.Context, n int) (int, error) {
+			if n < 0 {
+				return 0, errors.New("negative")
+			}
+			return n, nil
+		}).
+		Create(); err != nil {
+		t.Fatalf("register %s failed: %v", svc, err)
+	}
+
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer func() {
+		register.Stop()
+		_ = register.DeregisterAll(context.Background())
+		hub.Close()
+	}()
+	gncfg.UseConfig(gncfg.GlobalConfig{HubAddr: hub.URL, NodeAddr: "127.0.0.1" + gncfg.DefNodePort})
+	if err := register.Start(); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+
+	engine := gnhttp.NewEngine()
+	path := "/" + svc + "/Check"
+	performRequest(engine, http.MethodPost, path, []byte(`{"args":{"arg0":1}}`))
+	performRequest(engine, http.MethodPost, path, []byte(`{"args":{"arg0":2}}`))
+	performRequest(engine, http.MethodPost, path, []byte(`{"args":{"arg0":-1}}`))
+	performRequest(engine, http.MethodPost, path, []byte(`{"args":{"arg0":"x"}}`))
+
+	scrape := func() string {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set("Accept-Encoding", "identity")
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("unexpected /metrics status %d", w.Code)
+		}
+		return w.Body.String()
+	}
+
+	labels := fmt.Sprintf(`method="Check",service="%s"`, svc)
+	body := scrape()
+	for _, want := range []string{
+		fmt.Sprintf(`gn_calls_total{method="Check",outcome="ok",service="%s"} 2`, svc),
+		fmt.Sprintf(`gn_calls_total{method="Check",outcome="business_error",service="%s"} 1`, svc),
+		fmt.Sprintf(`gn_calls_total{method="Check",outcome="transport_error",service="%s"} 1`, svc),
+		fmt.Sprintf(`gn_call_duration_seconds_count{%s} 4`, labels),
+		fmt.Sprintf(`gn_calls_in_flight{%s} 0`, labels),
+		fmt.Sprintf(`gn_hub_last_registration_timestamp_seconds{service="%s"}`, svc),
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("metrics missing %q", want)
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(body, `gn_hub_heartbeats_total{result="success"}`) {
+		if time.Now().After(deadline) {
+			t.Fatalf("heartbeat success was not counted")
+		}
+		time.Sleep(20 * time.Millisecond)
+		body = scrape()
+	}
+}
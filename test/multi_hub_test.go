@@ -0,0 +1,157 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jom-io/gorig-node/client/register"
+	"github.com/jom-io/gorig-node/gncfg"
+)
+
+// countingHub records which of the node's requests mention svc.
+type countingHub struct {
+	*httptest.Server
+	mu    sync.Mutex
+	calls map[string]int // path -> requests naming svc
+}
+
+func newCountingHub(svc string) *countingHub {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	return newCountingHubOn(svc, ln)
+}
+
+// newCountingHubOn is newCountingHub serving on ln.
+func newCountingHubOn(svc string, ln net.Listener) *countingHub {
+	h := &countingHub{calls: map[string]int{}}
+	h.Server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), svc) {
+			h.mu.Lock()
+			h.calls[r.URL.Path]++
+			h.mu.Unlock()
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	h.Server.Listener.Close()
+	h.Server.Listener = ln
+	h.Server.Start()
+	return h
+}
+
+func (h *countingHub) count(path string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.calls[path]
+}
+
+func (h *countingHub) reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.calls = map[string]int{}
+}
+
+// With several hubs the node registers with all of them or fails over past the dead ones.
+func TestMultipleHubs(t *testing.T) {
+	svc := fmt.Sprintf("MultiHubSvc_%d", time.Now().UnixNano())
+	if err := register.Server(svc).RegName("Noop", func(ctx context.Context) error { return nil }).Create(); err != nil {
+		t.Fatalf("register %s failed: %v", svc, err)
+	}
+
+	dead := httptest.NewServer(http.NotFoundHandler())
+	deadAddr := dead.URL
+	dead.Close()
+	a, b := newCountingHub(svc), newCountingHub(svc)
+	defer func() {
+		register.Stop()
+		_ = register.DeregisterAll(context.Background())
+		a.Close()
+		b.Close()
+	}()
+
+	waitFor := func(what string, cond func() bool) {
+		deadline := time.Now().Add(2 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s", what)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	gncfg.UseConfig(gncfg.GlobalConfig{
+		HubAddr:  strings.Join([]string{deadAddr, a.URL, b.URL}, ", "),
+		NodeAddr: "127.0.0.1" + gncfg.DefNodePort,
+		RetryMin: 20 * time.Millisecond,
+		RetryMax: 50 * time.Millisecond,
+	})
+	if err := register.Start(); err != nil {
+		t.Fatalf("start should succeed while some hubs are up: %v", err)
+	}
+	if a.count("/register") != 1 || b.count("/register") != 1 {
+		t.Fatalf("policy all should register with every live hub: a=%d b=%d", a.count("/register"), b.count("/register"))
+	}
+	waitFor("heartbeats on both hubs", func() bool { return a.count("/heartbeat") > 0 && b.count("/heartbeat") > 0 })
+
+	hubs := register.Hubs()
+	if len(hubs) != 3 || hubs[0].Healthy || hubs[0].Failures == 0 || !hubs[1].Healthy || !hubs[2].Healthy {
+		t.Fatalf("unexpected hub health: %+v", hubs)
+	}
+	if !slices.Contains(hubs[0].Pending, svc) || len(hubs[1].Pending) != 0 {
+		t.Fatalf("the refusing hub should list the service as pending: %+v", hubs)
+	}
+
+	// the hub comes back: the node registers with it directly instead of waiting for a heartbeat
+	if ln, err := net.Listen("tcp", strings.TrimPrefix(deadAddr, "http://")); err == nil {
+		revived := newCountingHubOn(svc, ln)
+		waitFor("registration with the revived hub", func() bool { return revived.count("/register") > 0 })
+		waitFor("the pending list to drop the service", func() bool { return !slices.Contains(register.Hubs()[0].Pending, svc) })
+		revived.Close() // dead again for the failover case below
+	} else {
+		t.Logf("cannot reuse %s to revive the hub: %v", deadAddr, err)
+	}
+
+	// failover: the dead primary is skipped, the first live hub takes everything
+	register.Stop()
+	a.reset()
+	b.reset()
+	aURL, _ := url.Parse(a.URL)
+	gncfg.UseConfig(gncfg.GlobalConfig{
+		HubAddr:   strings.Join([]string{deadAddr, "dns://localhost:" + aURL.Port(), b.URL}, ","),
+		HubPolicy: gncfg.HubPolicyFailover,
+		NodeAddr:  "127.0.0.1" + gncfg.DefNodePort,
+	})
+	if err := register.Start(); err != nil {
+		t.Fatalf("failover start failed: %v", err)
+	}
+	waitFor("heartbeat on the failover hub", func() bool { return a.count("/heartbeat") > 0 })
+	if a.count("/register") != 1 || b.count("/register") != 0 || b.count("/heartbeat") != 0 {
+		t.Fatalf("failover should only use the first live hub: a=%d b=%d/%d", a.count("/register"), b.count("/register"), b.count("/heartbeat"))
+	}
+
+	found := false
+	for _, h := range register.Hubs() {
+		if h.Addr == "127.0.0.1:"+aURL.Port() {
+			found = h.Healthy
+		}
+	}
+	if !found {
+		t.Fatalf("dns entry was not expanded to a healthy hub: %+v", register.Hubs())
+	}
+
+	if err := (gncfg.GlobalConfig{HubPolicy: "random"}).Validate(); err == nil {
+		t.Fatalf("unknown hub policy should be rejected")
+	}
+}
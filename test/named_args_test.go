@@ -0,0 +1,106 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jom-io/gorig-node/client/inbound/gnhttp"
+	"github.com/jom-io/gorig-node/client/register"
+)
+
+// Requests keyed by argument names, or a bare body, decode like positional ones.
+func TestNamedArgsRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	svc := fmt.Sprintf("NamedArgsSvc_%d", time.Now().UnixNano())
+
+	type searchReq struct {
+		Keyword string `json:"keyword"`
+	}
+	type searchResp struct {
+		Summary string `json:"summary"`
+	}
+
+	if err := register.Server(svc).
+		RegName("Search", func(ctx context.Context, req searchReq, page int) (searchResp, error) {
+			return searchResp{Summary: fmt.Sprintf("%s@%d", req.Keyword, page)}, nil
+		}, "req", "page").
+		RegName("Find", func(ctx context.Context, req searchReq) (searchResp, error) {
+			return searchResp{Summary: req.Keyword}, nil
+		}).
+		RegName("Sum", func(ctx context.Context, nums []int) (int, error) {
+			total := 0
+			for _, n := range nums {
+				total += n
+			}
+			return total, nil
+		}).
+		RegName("Join", func(ctx context.Context, a, b string) (string, error) { return a + "|" + b, nil }).
+		Create(); err != nil {
+		t.Fatalf("register %s failed: %v", svc, err)
+	}
+
+	engine := gnhttp.NewEngine()
+	servers := register.RegisteredServers()
+
+	cases := []struct {
+		method string
+		body   string
+		want   string
+	}{
+		{"Search", `{"args":{"arg0":{"keyword":"go"},"arg1":2}}`, `{"resp":{"resp0":{"summary":"go@2"}},"error":""}`},
+		{"Search", `{"args":{"req":{"keyword":"go"},"page":3}}`, `{"resp":{"resp0":{"summary":"go@3"}},"error":""}`},
+		{"Search", `{"req":{"keyword":"go"},"page":4}`, `{"resp":{"resp0":{"summary":"go@4"}},"error":""}`},
+		{"Find", `{"keyword":"bare"}`, `{"resp":{"resp0":{"summary":"bare"}},"error":""}`},
+		{"Find", `{"searchReq":{"keyword":"named"}}`, `{"resp":{"resp0":{"summary":"named"}},"error":""}`},
+		{"Sum", `[1,2,3]`, `{"resp":{"resp0":6},"error":""}`},
+		{"Sum", `{"ints":[4,5]}`, `{"resp":{"resp0":9},"error":""}`},
+		{"Join", `{"string":"x","string1":"y"}`, `{"resp":{"resp0":"x|y"},"error":""}`}, // inferred names made unique
+	}
+
+	for _, tc := range cases {
+		if _, ok := servers[svc].MethodMeta[tc.method]; !ok {
+			t.Fatalf("method %s not registered", tc.method)
+		}
+		resp := performRequest(engine, http.MethodPost, "/"+svc+"/"+tc.method, []byte(tc.body))
+		if resp.Code != http.StatusOK {
+			t.Fatalf("%s %s: unexpected status %d, body=%s", tc.method, tc.body, resp.Code, resp.Body.String())
+		}
+		if got := resp.Body.String(); got != tc.want {
+			t.Fatalf("%s %s: got %s, want %s", tc.method, tc.body, got, tc.want)
+		}
+	}
+
+	for _, body := range []string{
+		`{"req":{"keyword":"go"},"page":"x"}`,     // mistyped
+		`{"req":{"keyword":"go"}}`,                // missing
+		`{"req":{"keyword":"go"},"pgae":1}`,       // misspelled name
+		`{"args":{"arg0":{},"arg1":1,"arg2":2}}`,  // position past the last argument
+		`{"args":{"arg0":{},"arg1":1},"extra":1}`, // unknown key next to the envelope
+		``, // nothing at all
+	} {
+		bad := performRequest(engine, http.MethodPost, "/"+svc+"/Search", []byte(body))
+		if bad.Code != http.StatusBadRequest || !strings.Contains(bad.Body.String(), register.CodeBadRequest) {
+			t.Fatalf("expected 400 for %q, got %d: %s", body, bad.Code, bad.Body.String())
+		}
+	}
+
+	// one name must not feed two arguments
+	if names := servers[svc].MethodMeta["Join"].ArgNames; len(names) != 2 || names[0] == names[1] {
+		t.Fatalf("inferred arg names should be unique: %v", names)
+	}
+	if bad := performRequest(engine, http.MethodPost, "/"+svc+"/Join", []byte(`{"string":"x"}`)); bad.Code != http.StatusBadRequest {
+		t.Fatalf("Join without its second argument should be rejected, got %d: %s", bad.Code, bad.Body.String())
+	}
+	err := register.Server(svc+"_Dup").
+		RegName("Join", func(ctx context.Context, a, b string) (string, error) { return a + b, nil }, "a", "a").
+		Create()
+	if err == nil || !strings.Contains(err.Error(), "duplicate arg name") {
+		t.Fatalf("duplicate arg names should fail registration, got %v", err)
+	}
+}
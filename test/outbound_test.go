@@ -0,0 +1,109 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jom-io/gorig-node/client/inbound/gnhttp"
+	"github.com/jom-io/gorig-node/client/outbound"
+	"github.com/jom-io/gorig-node/client/register"
+	"github.com/jom-io/gorig-node/gncfg"
+)
+
+// Call a registered service through a fake hub using both typed and untyped outbound APIs.
+func TestOutboundInvoke(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	svc := fmt.Sprintf("OutboundSvc_%d", time.Now().UnixNano())
+
+	type sumReq struct {
+		A int `json:"a"`
+		B int `json:"b"`
+	}
+	type sumResp struct {
+		Sum int `json:"sum"`
+	}
+
+	if err := register.Server(svc).
+		RegName("Sum", func(ctx context.Context, req sumReq) (sumResp, error) {
+			if req.A < 0 {
+				return sumResp{}, errors.New("negative input")
+			}
+			return sumResp{Sum: req.A + req.B}, nil
+		}).
+		Create(); err != nil {
+		t.Fatalf("register %s failed: %v", svc, err)
+	}
+
+	node := httptest.NewServer(gnhttp.NewEngine())
+	defer node.Close()
+
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/discover" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		var req struct {
+			Service string `json:"service"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		resp := map[string]interface{}{"service": req.Service, "instances": []register.Instance{}}
+		if req.Service == svc {
+			resp["instances"] = []register.Instance{{Service: svc, Host: node.URL}}
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer func() {
+		register.StopDiscovery()
+		hub.Close()
+	}()
+
+	gncfg.UseConfig(gncfg.GlobalConfig{HubAddr: hub.URL})
+
+	var sum func(ctx context.Context, req sumReq) (sumResp, error)
+	if err := outbound.Service(svc).Bind("Sum", &sum); err != nil {
+		t.Fatalf("bind failed: %v", err)
+	}
+
+	resp, err := sum(context.Background(), sumReq{A: 1, B: 2})
+	if err != nil {
+		t.Fatalf("typed call failed: %v", err)
+	}
+	if resp.Sum != 3 {
+		t.Fatalf("unexpected sum: %d", resp.Sum)
+	}
+
+	if _, err := sum(context.Background(), sumReq{A: -1}); err == nil || err.Error() != "negative input" {
+		t.Fatalf("expected remote error, got %v", err)
+	}
+
+	var out sumResp
+	if err := outbound.Service(svc).Call(context.Background(), "Sum", []interface{}{sumReq{A: 4, B: 5}}, &out); err != nil {
+		t.Fatalf("untyped call failed: %v", err)
+	}
+	if out.Sum != 9 {
+		t.Fatalf("unexpected untyped sum: %d", out.Sum)
+	}
+
+	if err := outbound.Service(svc).Codec(register.MsgpackCodec).Call(context.Background(), "Sum", []interface{}{sumReq{A: 6, B: 7}}, &out); err != nil {
+		t.Fatalf("msgpack call failed: %v", err)
+	}
+	if out.Sum != 13 {
+		t.Fatalf("unexpected msgpack sum: %d", out.Sum)
+	}
+
+	var missing func(ctx context.Context) error
+	if err := outbound.Service("MissingSvc").Bind("Nothing", &missing); err != nil {
+		t.Fatalf("bind failed: %v", err)
+	}
+	if err := missing(context.Background()); !errors.Is(err, outbound.ErrNoInstance) {
+		t.Fatalf("expected ErrNoInstance, got %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jom-io/gorig-node/client/inbound/gnhttp"
+	"github.com/jom-io/gorig-node/client/register"
+)
+
+// A panicking handler answers with the regular envelope and is counted per method.
+func TestHandlerPanic(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	svc := fmt.Sprintf("PanicSvc_%d", time.Now().UnixNano())
+
+	if err := register.Server(svc).
+		RegName("Boom", func(ctx context.Context, n int) (int, error) {
+			var m map[string]int
+			m["x"] = n // nil map write
+			return n, nil
+		}).
+		RegName("Fine", func(ctx context.Context) (string, error) {
+			return "ok", nil
+		}).
+		Create(); err != nil {
+		t.Fatalf("register %s failed: %v", svc, err)
+	}
+
+	engine := gnhttp.NewEngine()
+	panics := func(method string) string {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set("Accept-Encoding", "identity")
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		prefix := fmt.Sprintf(`gn_handler_panics_total{method="%s",service="%s"} `, method, svc)
+		for _, line := range strings.Split(w.Body.String(), "\n") {
+			if strings.HasPrefix(line, prefix) {
+				return strings.TrimPrefix(line, prefix)
+			}
+		}
+		return ""
+	}
+
+	for i := 1; i <= 2; i++ {
+		resp := performRequest(engine, http.MethodPost, "/"+svc+"/Boom", []byte(`{"args":{"arg0":1}}`))
+		if resp.Code != http.StatusInternalServerError {
+			t.Fatalf("unexpected status %d, body=%s", resp.Code, resp.Body.String())
+		}
+		var w register.WrappedResponse
+		if err := json.Unmarshal(resp.Body.Bytes(), &w); err != nil {
+			t.Fatalf("panic answer is not an envelope: %v, body=%s", err, resp.Body.String())
+		}
+		if w.ErrorInfo == nil || w.ErrorInfo.Code != register.CodeInternal || w.ErrorInfo.TraceID != "test-request-id" {
+			t.Fatalf("unexpected error info: %+v", w.ErrorInfo)
+		}
+		if got := panics("Boom"); got != fmt.Sprint(i) {
+			t.Fatalf("expected %d panics, got %q", i, got)
+		}
+	}
+
+	// the node keeps serving other calls
+	resp := performRequest(engine, http.MethodPost, "/"+svc+"/Fine", nil)
+	if resp.Code != http.StatusOK || resp.Body.String() != `{"resp":{"resp0":"ok"},"error":""}` {
+		t.Fatalf("unexpected answer after panic: %d %s", resp.Code, resp.Body.String())
+	}
+	if got := panics("Fine"); got != "" {
+		t.Fatalf("expected no panics for Fine, got %s", got)
+	}
+}
@@ -0,0 +1,183 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jom-io/gorig-node/client/inbound/gnhttp"
+	"github.com/jom-io/gorig-node/client/register"
+	"github.com/jom-io/gorig-node/gncfg"
+)
+
+// /readyz follows registration, listener, health check and drain state; health goes to the hub too.
+func TestReadinessProbe(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	svc := fmt.Sprintf("ProbeSvc_%d", time.Now().UnixNano())
+
+	var healthy atomic.Bool
+	if err := register.Server(svc).
+		RegName("Ping", func(ctx context.Context) (string, error) { return "pong", nil }).
+		HealthCheck(func(ctx context.Context) error {
+			if !healthy.Load() {
+				return errors.New("db unreachable")
+			}
+			return nil
+		}).
+		Create(); err != nil {
+		t.Fatalf("register %s failed: %v", svc, err)
+	}
+
+	var (
+		mu         sync.Mutex
+		heartbeats []string
+	)
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/heartbeat" {
+			body, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			heartbeats = append(heartbeats, string(body))
+			mu.Unlock()
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer func() {
+		register.Stop()
+		_ = register.DeregisterAll(context.Background())
+		hub.Close()
+	}()
+
+	engine := gnhttp.NewEngine()
+	probe := func(path string) (int, map[string]interface{}) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Encoding", "identity")
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		var body map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("decode %s failed: %v, body=%s", path, err, w.Body.String())
+		}
+		return w.Code, body
+	}
+
+	if code, _ := probe("/healthz"); code != http.StatusOK {
+		t.Fatalf("liveness should always pass, got %d", code)
+	}
+	if code, body := probe("/readyz"); code != http.StatusServiceUnavailable || body["listening"] != false {
+		t.Fatalf("node without listener must not be ready: %d %v", code, body)
+	}
+
+	gncfg.UseConfig(gncfg.GlobalConfig{
+		HubAddr:           hub.URL,
+		NodeAddr:          "127.0.0.1" + gncfg.DefNodePort,
+		HeartbeatInterval: 50 * time.Millisecond,
+		HealthInterval:    20 * time.Millisecond,
+	})
+	if err := register.Start(); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	if err := gnhttp.Start(gncfg.DefNodePort); err != nil {
+		t.Skipf("inbound port unavailable: %v", err)
+	}
+	defer func() { _ = gnhttp.Shutdown(context.Background()) }()
+
+	// health checks run in the background; probes and heartbeats read the cached result
+	eventually := func(what string, cond func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s", what)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	eventually("a failing readiness probe", func() bool {
+		code, body := probe("/readyz")
+		failing, _ := body["failing"].(map[string]interface{})
+		return code == http.StatusServiceUnavailable && strings.Contains(fmt.Sprint(failing[svc]), "db unreachable")
+	})
+	eventually("a heartbeat reporting the failing check", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, sent := range heartbeats {
+			if strings.Contains(sent, svc) && strings.Contains(sent, `"health":"failing"`) && strings.Contains(sent, "db unreachable") {
+				return true
+			}
+		}
+		return false
+	})
+
+	healthy.Store(true)
+	eventually("the node to turn ready", func() bool {
+		code, body := probe("/readyz")
+		return code == http.StatusOK && body["ready"] == true
+	})
+
+	// taking one service out of rotation must not pull the whole node
+	other := svc + "_Other"
+	if err := register.Server(other).
+		RegName("Ping", func(ctx context.Context) (string, error) { return "pong", nil }).
+		Create(); err != nil {
+		t.Fatalf("register %s failed: %v", other, err)
+	}
+	if err := register.Start(); err != nil {
+		t.Fatalf("restart failed: %v", err)
+	}
+	if err := register.Deregister(other); err != nil {
+		t.Fatalf("deregister %s failed: %v", other, err)
+	}
+	if code, body := probe("/readyz"); code != http.StatusOK || body["ready"] != true {
+		t.Fatalf("a deliberately deregistered service must keep the node ready: %d %v", code, body)
+	}
+
+	// a check that hangs must not hold up heartbeats
+	slow := svc + "_Slow"
+	released := make(chan struct{})
+	defer close(released)
+	if err := register.Server(slow).
+		RegName("Ping", func(ctx context.Context) (string, error) { return "pong", nil }).
+		HealthCheck(func(ctx context.Context) error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-released:
+				return nil // a later Start in this process may register the service again
+			}
+		}).
+		Create(); err != nil {
+		t.Fatalf("register %s failed: %v", slow, err)
+	}
+	if err := register.Start(); err != nil {
+		t.Fatalf("restart failed: %v", err)
+	}
+	mu.Lock()
+	before := len(heartbeats)
+	mu.Unlock()
+	time.Sleep(500 * time.Millisecond)
+	mu.Lock()
+	beats := len(heartbeats) - before
+	mu.Unlock()
+	if beats < 3 {
+		t.Fatalf("a hanging health check stalled heartbeats: %d in 500ms", beats)
+	}
+	if code, body := probe("/readyz"); code != http.StatusServiceUnavailable || !strings.Contains(fmt.Sprint(body["failing"]), register.ErrHealthPending.Error()) {
+		t.Fatalf("a service whose first check has not finished must keep the node unready: %d %v", code, body)
+	}
+
+	gnhttp.Drain()
+	if code, body := probe("/readyz"); code != http.StatusServiceUnavailable || body["draining"] != true {
+		t.Fatalf("draining node must not be ready: %d %v", code, body)
+	}
+}
@@ -196,6 +196,30 @@ func TestRegisterFlow(t *testing.T) {
 	} else if host != "10.0.0.5:8081" {
 		t.Fatalf("order service heartbeat host mismatch: %s", host)
 	}
+
+	// Deregister takes one service out of rotation at runtime.
+	if err := register.Deregister(userService); err != nil {
+		t.Fatalf("Deregister() failed: %v", err)
+	}
+	deregTimeout := time.After(2 * time.Second)
+	for deregistered := false; !deregistered; {
+		select {
+		case req := <-reqCh:
+			if req.Path != "/deregister" {
+				continue
+			}
+			var body regPayload
+			if err := json.Unmarshal(req.Body, &body); err != nil {
+				t.Fatalf("failed to parse deregister payload: %v", err)
+			}
+			if body.Service != userService || body.Host != gncfg.Cfg.NodeAddr {
+				t.Fatalf("unexpected deregister payload: %+v", body)
+			}
+			deregistered = true
+		case <-deregTimeout:
+			t.Fatalf("timed out waiting for deregister request")
+		}
+	}
 }
 
 // Copy the auto-detect IP logic to check if prerequisites are satisfied.
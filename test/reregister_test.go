@@ -0,0 +1,80 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jom-io/gorig-node/client/register"
+	"github.com/jom-io/gorig-node/gncfg"
+)
+
+// A failed initial registration is retried, and a hub that forgets the service triggers a replay.
+func TestReRegisterFlow(t *testing.T) {
+	svc := fmt.Sprintf("ReRegSvc_%d", time.Now().UnixNano())
+
+	var registers atomic.Int32
+	registered := make(chan int32, 16)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/register":
+			var req struct {
+				Service string `json:"service"`
+			}
+			_ = json.Unmarshal(body, &req)
+			if req.Service != svc {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+			n := registers.Add(1)
+			if n == 1 {
+				// hub not ready yet: first attempt fails
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			registered <- n
+			w.WriteHeader(http.StatusOK)
+		case "/heartbeat":
+			// pretend the hub restarted and lost the service
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"unknown": []string{svc}})
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer func() {
+		register.Stop()
+		ts.Close()
+	}()
+
+	gncfg.UseConfig(gncfg.GlobalConfig{
+		HubAddr:  ts.URL,
+		NodeAddr: "127.0.0.1" + gncfg.DefNodePort,
+	})
+
+	if err := register.Server(svc).RegName("Noop", func(ctx context.Context) error { return nil }).Create(); err != nil {
+		t.Fatalf("register %s failed: %v", svc, err)
+	}
+
+	if err := register.Start(); err == nil {
+		t.Fatalf("Start() should report the failed first registration")
+	}
+
+	timeout := time.After(5 * time.Second)
+	for _, want := range []int32{2, 3} {
+		select {
+		case n := <-registered:
+			if n != want {
+				t.Fatalf("unexpected register attempt %d, want %d", n, want)
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for register attempt %d", want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jom-io/gorig-node/client/inbound/gnhttp"
+	"github.com/jom-io/gorig-node/client/outbound"
+	"github.com/jom-io/gorig-node/client/register"
+	"github.com/jom-io/gorig-node/gncfg"
+)
+
+// Calls move to another instance only when the first one never received them.
+func TestOutboundRetry(t *testing.T) {
+	var runs atomic.Int32
+	cut := func() *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			runs.Add(1) // the handler ran, then the response is lost
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				_ = conn.Close()
+			}
+		}))
+	}
+	cutA, cutB := cut(), cut()
+	defer cutA.Close()
+	defer cutB.Close()
+
+	cutSvc := fmt.Sprintf("CutSvc_%d", time.Now().UnixNano())
+	refusedSvc := fmt.Sprintf("RefusedSvc_%d", time.Now().UnixNano())
+	if err := register.Server(refusedSvc).
+		RegName("Create", func(ctx context.Context) (string, error) { return "ok", nil }).
+		Create(); err != nil {
+		t.Fatalf("register %s failed: %v", refusedSvc, err)
+	}
+	live := httptest.NewServer(gnhttp.NewEngine())
+	defer live.Close()
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+	instances := map[string][]register.Instance{
+		cutSvc:     {{Service: cutSvc, Host: cutA.URL}, {Service: cutSvc, Host: cutB.URL}},
+		refusedSvc: {{Service: refusedSvc, Host: dead.URL}, {Service: refusedSvc, Host: live.URL}},
+	}
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Service string `json:"service"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"service": req.Service, "instances": instances[req.Service]})
+	}))
+	defer func() {
+		register.StopDiscovery()
+		hub.Close()
+	}()
+	gncfg.UseConfig(gncfg.GlobalConfig{HubAddr: hub.URL})
+
+	var out string
+	if err := outbound.Service(cutSvc).Call(context.Background(), "Create", nil, &out); err == nil {
+		t.Fatalf("a call whose response was cut off should fail")
+	}
+	if n := runs.Load(); n != 1 {
+		t.Fatalf("a call that reached a handler must not be retried, handlers ran %d times", n)
+	}
+
+	// a refused connection never reached the handler, so the next instance takes the call
+	for i := 0; i < 4; i++ {
+		if err := outbound.Service(refusedSvc).Call(context.Background(), "Create", nil, &out); err != nil || out != "ok" {
+			t.Fatalf("call %d should move past the refusing instance: %q %v", i, out, err)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jom-io/gorig-node/client/inbound/gnhttp"
+	"github.com/jom-io/gorig-node/client/register"
+	"github.com/jom-io/gorig-node/gncfg"
+)
+
+// Calls above the adaptive limit are shed with a retryable 503, and heartbeats report it.
+func TestAdaptiveShedding(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	svc := fmt.Sprintf("ShedSvc_%d", time.Now().UnixNano())
+	entered := make(chan struct{})
+	unblock := make(chan struct{})
+	held := make(chan struct{})
+	if err := register.Server(svc).
+		RegName("Fast", func(ctx context.Context) (string, error) { return "ok", nil }).
+		RegName("Slow", func(ctx context.Context) (string, error) {
+			entered <- struct{}{}
+			<-unblock
+			return "done", nil
+		}).
+		RegName("Held", func(ctx context.Context) (string, error) {
+			entered <- struct{}{}
+			<-held
+			return "done", nil
+		}).
+		Create(); err != nil {
+		t.Fatalf("register %s failed: %v", svc, err)
+	}
+
+	var (
+		mu         sync.Mutex
+		heartbeats []string
+	)
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/heartbeat" {
+			body, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			heartbeats = append(heartbeats, string(body))
+			mu.Unlock()
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer func() {
+		register.Stop()
+		_ = register.DeregisterAll(context.Background())
+		hub.Close()
+	}()
+	const maxLimit = 8
+	gncfg.UseConfig(gncfg.GlobalConfig{
+		HubAddr:           hub.URL,
+		NodeAddr:          "127.0.0.1" + gncfg.DefNodePort,
+		AdaptiveLimit:     true,
+		AdaptiveMaxLimit:  maxLimit,
+		HeartbeatInterval: 50 * time.Millisecond,
+	})
+
+	engine := gnhttp.NewEngine()
+	call := func(method string) (int, *register.Error) {
+		w := performRequest(engine, http.MethodPost, "/"+svc+"/"+method, []byte(`{}`))
+		var resp register.WrappedResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp.ErrorInfo
+	}
+
+	// nothing is shed before the first call sets a latency baseline, even above the ceiling
+	early := make(chan int, 3*maxLimit)
+	for i := 0; i < 3*maxLimit; i++ {
+		go func() {
+			code, _ := call("Held")
+			early <- code
+		}()
+		<-entered
+	}
+	close(held)
+	for i := 0; i < 3*maxLimit; i++ {
+		if code := <-early; code != http.StatusOK {
+			t.Fatalf("call before the baseline was shed: %d", code)
+		}
+	}
+
+	// fast calls settle the latency baseline; the limit stays at the configured ceiling
+	for i := 0; i < 20; i++ {
+		if code, e := call("Fast"); code != http.StatusOK {
+			t.Fatalf("fast call %d failed: %d %+v", i, code, e)
+		}
+	}
+
+	done := make(chan int, maxLimit)
+	for i := 0; i < maxLimit; i++ {
+		go func() {
+			code, _ := call("Slow")
+			done <- code
+		}()
+		<-entered
+	}
+	code, e := call("Fast")
+	if code != http.StatusServiceUnavailable || e == nil || e.Code != register.CodeOverloaded || !e.Retryable || e.Details["limit"] != "adaptive" {
+		t.Fatalf("call over the limit should be shed: %d %+v", code, e)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(unblock)
+	for i := 0; i < maxLimit; i++ {
+		if code := <-done; code != http.StatusOK {
+			t.Fatalf("admitted call failed with %d", code)
+		}
+	}
+
+	// the slow calls pushed latency well above the baseline, so the limit shrinks
+	var health struct {
+		Shedding         bool  `json:"shedding"`
+		ConcurrencyLimit int64 `json:"concurrency_limit"`
+	}
+	w := performRequest(engine, http.MethodGet, "/_gn/health", nil)
+	if err := json.Unmarshal(w.Body.Bytes(), &health); err != nil {
+		t.Fatalf("decode health failed: %v", err)
+	}
+	if !health.Shedding || health.ConcurrencyLimit < 1 || health.ConcurrencyLimit >= maxLimit {
+		t.Fatalf("expected a shrunk limit while shedding: %s", w.Body.String())
+	}
+
+	register.Stop() // a loop left by an earlier test would not beat right away
+	mu.Lock()
+	seen := len(heartbeats)
+	mu.Unlock()
+	if err := register.Start(); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		got := append([]string(nil), heartbeats[seen:]...)
+		mu.Unlock()
+		if len(got) > 1 {
+			if !strings.Contains(got[0], `"status":"shedding"`) || !strings.Contains(got[0], fmt.Sprintf(`"concurrency_limit":%d`, health.ConcurrencyLimit)) {
+				t.Fatalf("heartbeat should report shedding and the learned limit: %s", got[0])
+			}
+			// the hub has been told; without new sheds the next beat is back to serving
+			if strings.Contains(got[1], `"status":"shedding"`) {
+				t.Fatalf("shedding should be reported once per episode: %s", got[1])
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected two heartbeats, got %d", len(got))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,128 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jom-io/gorig-node/client/inbound/gnhttp"
+	"github.com/jom-io/gorig-node/client/outbound"
+	"github.com/jom-io/gorig-node/client/register"
+	"github.com/jom-io/gorig-node/gncfg"
+	"github.com/jom-io/gorig-node/gntrace"
+	"github.com/jom-io/gorig-node/gntrace/gntracetest"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// One trace spans the caller, the hub lookup and the server span the handler sees.
+func TestTracePropagation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	exp := gntracetest.UseInMemoryExporter()
+	defer gntrace.UseTracerProvider(nil)
+
+	svc := fmt.Sprintf("TraceSvc_%d", time.Now().UnixNano())
+
+	if err := register.Server(svc).
+		RegName("TraceID", func(ctx context.Context, fail bool) (string, error) {
+			if fail {
+				return "", errors.New("failed on purpose")
+			}
+			return trace.SpanFromContext(ctx).SpanContext().TraceID().String(), nil
+		}).
+		Create(); err != nil {
+		t.Fatalf("register %s failed: %v", svc, err)
+	}
+
+	node := httptest.NewServer(gnhttp.NewEngine())
+	defer node.Close()
+
+	var (
+		mu         sync.Mutex
+		hubParents []string
+	)
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hubParents = append(hubParents, r.Header.Get("traceparent"))
+		mu.Unlock()
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"service":   svc,
+			"instances": []register.Instance{{Service: svc, Host: node.URL}},
+		})
+	}))
+	defer func() {
+		register.StopDiscovery()
+		hub.Close()
+	}()
+	gncfg.UseConfig(gncfg.GlobalConfig{HubAddr: hub.URL})
+
+	ctx, root := gntrace.Tracer().Start(context.Background(), "caller")
+	traceID := root.SpanContext().TraceID()
+
+	var got string
+	client := outbound.Service(svc)
+	if err := client.Call(ctx, "TraceID", []interface{}{false}, &got); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if got != traceID.String() {
+		t.Fatalf("handler saw trace %s, want %s", got, traceID)
+	}
+	if err := client.Call(ctx, "TraceID", []interface{}{true}, &got); err == nil {
+		t.Fatalf("expected business error")
+	}
+	root.End()
+
+	mu.Lock()
+	if len(hubParents) == 0 || hubParents[0] == "" {
+		t.Fatalf("hub request carried no traceparent: %v", hubParents)
+	}
+	mu.Unlock()
+
+	var servers, clients []tracetest.SpanStub
+	hubSpan := false
+	for _, s := range exp.GetSpans() {
+		if s.Name == "hub/discover" {
+			hubSpan = true
+		}
+		if s.Name != svc+"/TraceID" {
+			continue
+		}
+		if s.SpanContext.TraceID() != traceID {
+			t.Fatalf("span %s left the caller's trace", s.Name)
+		}
+		switch s.SpanKind {
+		case trace.SpanKindServer:
+			servers = append(servers, s)
+		case trace.SpanKindClient:
+			clients = append(clients, s)
+		}
+	}
+	if !hubSpan {
+		t.Fatalf("expected a hub/discover span")
+	}
+	if len(servers) != 2 || len(clients) != 2 {
+		t.Fatalf("expected 2 server and 2 client spans, got %d and %d", len(servers), len(clients))
+	}
+	if servers[0].Parent.SpanID() != clients[0].SpanContext.SpanID() {
+		t.Fatalf("server span should be a child of the client span")
+	}
+	if servers[0].Status.Code == codes.Error || servers[1].Status.Code != codes.Error {
+		t.Fatalf("unexpected server span statuses: %v, %v", servers[0].Status, servers[1].Status)
+	}
+	attrs := map[string]string{}
+	for _, kv := range servers[1].Attributes {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	if attrs["rpc.service"] != svc || attrs["rpc.method"] != "TraceID" || attrs["gn.error"] != "failed on purpose" {
+		t.Fatalf("unexpected server span attributes: %v", attrs)
+	}
+}